@@ -0,0 +1,254 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+const (
+	// centralLocation is the location of the dashboard itself.
+	centralLocation = "central"
+	// agentInterval is how often agents run their probes by default,
+	// as often as the dashboard runs web probes.
+	agentInterval = time.Minute * 2
+	// locationStale is how old the latest result from a location may
+	// be before it no longer counts towards a quorum.
+	locationStale = agentInterval * 5
+	// quorumInterval is how often quorums are checked again, so alerts
+	// clear once locations stop reporting.
+	quorumInterval = time.Minute
+)
+
+// Locations makes the probe alert only once it fails in a quorum of
+// the locations, or a majority of them if quorum is 0. Results from
+// locations other than "central", the dashboard itself, are pushed by
+// agents.
+func Locations(quorum int, locations ...string) ProbeOption {
+	return func(r *probeRegistration) {
+		r.info.Locations = locations
+		r.info.Quorum = quorum
+	}
+}
+
+// quorum returns how many locations must fail for the probe to alert.
+func (i probeInfo) quorum() int {
+	if i.Quorum > 0 {
+		return i.Quorum
+	}
+	return len(i.Locations)/2 + 1
+}
+
+// runsHere returns true if the dashboard itself runs the probe.
+func (i probeInfo) runsHere() bool {
+	return len(i.Locations) == 0 || hasString(i.Locations, centralLocation)
+}
+
+// hasString returns true if the string is in the list.
+func hasString(ss []string, s string) bool {
+	for _, t := range ss {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// locationKey returns the name the results of the probe at the
+// location are kept under in the history.
+func locationKey(probe, location string) string {
+	if location == centralLocation {
+		return probe
+	}
+	return probe + "@" + location
+}
+
+// locationView is the latest result of a probe at a location, as
+// shown on the index page.
+type locationView struct {
+	Name  string
+	Last  *result // the latest result, if any
+	Stale bool    // whether the latest result is too old to count
+}
+
+// Failing returns true if the latest result from the location failed,
+// and is recent enough to count.
+func (l locationView) Failing() bool {
+	return l.Last != nil && !l.Stale && !l.Last.Passed
+}
+
+// getLocations returns the latest results of the probe in each of its
+// locations.
+func (d *Dashboard) getLocations(name string) []locationView {
+	now := time.Now()
+	ls := []locationView{}
+	for _, loc := range d.getProbeInfo(name).Locations {
+		l := locationView{Name: loc}
+		if rs, _ := d.history.results(locationKey(name, loc), 0, 1); len(rs) > 0 {
+			l.Last = &rs[0]
+			l.Stale = now.Sub(rs[0].Time) > locationStale
+		}
+		ls = append(ls, l)
+	}
+	return ls
+}
+
+// checkQuorum raises an alert for the probe if it's failing in a
+// quorum of its locations, or clears it otherwise.
+func (d *Dashboard) checkQuorum(p *prober.Probe) {
+	info := d.getProbeInfo(p.Name)
+	if len(info.Locations) == 0 {
+		return
+	}
+	d.quorumLock.Lock()
+	defer d.quorumLock.Unlock()
+	failing := []string{}
+	for _, l := range d.getLocations(p.Name) {
+		if l.Failing() {
+			failing = append(failing, l.Name)
+		}
+	}
+	if len(failing) >= info.quorum() {
+		// Resolvers are told about firing alerts on every check, while
+		// others are only notified as they're raised.
+		if d.alerts.get(p.Name).Alerting {
+			if _, ok := d.notifier.(resolver); !ok {
+				return
+			}
+		} else {
+			log.Printf("Probe %s is failing in %d of %d locations (%s)\n", p.Name, len(failing), len(info.Locations), strings.Join(failing, ", "))
+		}
+		if err := d.sendAlert(p.Name, p.Desc, p.Badness, p.Records); err != nil {
+			log.Printf("Failed to alert about %s: %v\n", p.Name, err)
+		}
+	} else if d.clearAlert(p.Name, info) {
+		log.Printf("Probe %s has recovered, failing in %d of %d locations\n", p.Name, len(failing), len(info.Locations))
+		d.incidents.probeRecovered(p.Name)
+		d.publishAlert(p.Name)
+	}
+}
+
+// quorumLoop checks the quorums of probes running in several
+// locations every quorumInterval until the context is done, so results
+// turn stale even when no more are pushed.
+func (d *Dashboard) quorumLoop(ctx context.Context) {
+	t := time.NewTicker(quorumInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			for _, p := range d.listProbes() {
+				d.checkQuorum(p)
+			}
+		}
+	}
+}
+
+// apiAgentProbes serves the config of the probes assigned to the
+// agent's location, with only what's needed to run them.
+func (d *Dashboard) apiAgentProbes(w http.ResponseWriter, r *http.Request, location string) {
+	probecfg := d.getProbeConfig()
+	assigned := probeConfig{}
+	for _, p := range probecfg.WebProbes {
+		if hasString(p.Locations, location) {
+			p.Escalation, p.Links, p.Locations, p.Quorum = "", nil, nil, 0
+			assigned.WebProbes = append(assigned.WebProbes, p)
+		}
+	}
+	for _, p := range probecfg.VarsProbes {
+		if hasString(p.Locations, location) {
+			p.Escalation, p.Links, p.Locations, p.Quorum = "", nil, nil, 0
+			assigned.VarsProbes = append(assigned.VarsProbes, p)
+		}
+	}
+	for _, p := range probecfg.DnsProbes {
+		if hasString(p.Locations, location) {
+			p.Escalation, p.Links, p.Locations, p.Quorum = "", nil, nil, 0
+			assigned.DnsProbes = append(assigned.DnsProbes, p)
+		}
+	}
+	serveJSON(w, assigned)
+}
+
+// apiAgentResults records the results of probes run by the agent at
+// the location, alerting if a quorum of locations fail.
+func (d *Dashboard) apiAgentResults(w http.ResponseWriter, r *http.Request, who string) {
+	if who == centralLocation {
+		http.Error(w, "Agents can't be at the central location.", http.StatusBadRequest)
+		return
+	}
+	results := []ProbeResult{}
+	if !decodeJSON(w, r, &results) {
+		return
+	}
+	probes := map[string]*prober.Probe{}
+	for _, res := range results {
+		p := d.findProbe(res.Name)
+		if p == nil || !hasString(d.getProbeInfo(res.Name).Locations, who) {
+			http.Error(w, fmt.Sprintf("Probe %s isn't assigned to location %s.", res.Name, who), http.StatusBadRequest)
+			return
+		}
+		probes[p.Name] = p
+	}
+	now := time.Now()
+	for _, res := range results {
+		if res.Time.IsZero() || res.Time.After(now) {
+			res.Time = now
+		}
+		d.history.record(locationKey(res.Name, who), prober.Result{Passed: res.Passed, Info: res.Info}, res.Time, res.Duration)
+	}
+	for _, p := range probes {
+		d.checkQuorum(p)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunAgent runs the probes assigned to the agent by the dashboard the
+// client talks to, pushing their results back to it every interval,
+// until the context is done. The agent's location is the one the
+// client's agent token is bound to.
+func RunAgent(ctx context.Context, c *Client, interval time.Duration) {
+	if interval <= 0 {
+		interval = agentInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := runAgentProbes(ctx, c, interval); err != nil {
+			log.Printf("Failed to run agent probes: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// runAgentProbes fetches and runs the probes assigned to the agent
+// once, pushing their results, with runs failing after the timeout.
+func runAgentProbes(ctx context.Context, c *Client, timeout time.Duration) error {
+	probecfg := probeConfig{}
+	if err := c.send("GET", "agent/probes", nil, "", &probecfg); err != nil {
+		return err
+	}
+	probes := newProbeLoader(probecfg).loadProbes()
+	if len(probes) == 0 {
+		log.Printf("No probes assigned to this agent\n")
+		return nil
+	}
+	rctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	results := runProbes(rctx, probes, probeOnce)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return c.postJSON("agent/results", results)
+}
@@ -0,0 +1,114 @@
+package dashboard
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"hkjn.me/prober"
+)
+
+func TestQuorum(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true, AgentTokens: map[string]string{"eu": "eu-token", "us": "us-token", "ap": "ap-token"}})
+	d.AddProbe(prober.NewProbe(fakeProber{}, "WebIndex", ""), Locations(2, "eu", "us", "ap"))
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	agents := map[string]*Client{}
+	for loc, token := range d.conf.AgentTokens {
+		agents[loc] = &Client{URL: srv.URL, Token: token}
+	}
+
+	cases := []struct {
+		location string
+		passed   bool
+		want     bool // whether WebIndex is alerting after the push
+	}{
+		{"eu", false, false},
+		{"us", true, false},
+		{"ap", false, true},
+		{"us", false, true},
+		{"eu", true, true},
+		{"ap", true, false},
+	}
+	for i, tt := range cases {
+		if err := agents[tt.location].postJSON("agent/results", []ProbeResult{{Name: "WebIndex", Passed: tt.passed}}); err != nil {
+			t.Fatalf("[%d] failed to push results from %s: %v\n", i, tt.location, err)
+		}
+		if got := d.alerts.get("WebIndex").Alerting; got != tt.want {
+			t.Fatalf("[%d] want alerting %v after %s passed=%v, got %v\n", i, tt.want, tt.location, tt.passed, got)
+		}
+	}
+	if got := d.getLocations("WebIndex"); len(got) != 3 || got[0].Last == nil || !got[0].Last.Passed || !got[1].Failing() {
+		t.Fatalf("want latest results per location, got %+v\n", got)
+	}
+	if err := agents["eu"].postJSON("agent/results", []ProbeResult{{Name: "Missing"}}); err == nil {
+		t.Fatalf("want error pushing result of unassigned probe, got none\n")
+	}
+}
+
+func TestQuorumStale(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true})
+	p := prober.NewProbe(fakeProber{}, "WebIndex", "")
+	d.AddProbe(p, Locations(2, "eu", "us"))
+	cases := []struct {
+		age  time.Duration // how long ago both locations reported failing
+		want bool          // whether WebIndex is alerting after checking the quorum
+	}{
+		{time.Minute, true},
+		{locationStale + time.Minute, false},
+	}
+	for i, tt := range cases {
+		for _, loc := range []string{"eu", "us"} {
+			d.history.record(locationKey("WebIndex", loc), prober.Result{Info: "down"}, time.Now().Add(-tt.age), 0)
+		}
+		d.checkQuorum(p)
+		if got := d.alerts.get("WebIndex").Alerting; got != tt.want {
+			t.Fatalf("[%d] want alerting %v with results %v old, got %v\n", i, tt.want, tt.age, got)
+		}
+	}
+}
+
+func TestAgentTokens(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true, APITokens: map[string]string{"ops": "ops-token"}, AgentTokens: map[string]string{"eu": "eu-token"}})
+	if err := yaml.Unmarshal([]byte(`
+webprobes:
+  - name: WebIndex
+    target: https://example.com
+    escalation: web-oncall
+    locations: [central, eu]
+  - name: WebAdmin
+    target: https://admin.example.com
+    locations: [central, us]
+`), &d.probecfg); err != nil {
+		t.Fatalf("failed to parse config: %v\n", err)
+	}
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	cases := []struct {
+		token, method, path string
+		wantErr             bool
+	}{
+		{"eu-token", "GET", "agent/probes", false},
+		{"ops-token", "GET", "agent/probes", true},
+		{"eu-token", "GET", "status", true},
+		{"eu-token", "POST", "probes/WebIndex/run", true},
+		{"ops-token", "GET", "status", false},
+	}
+	for i, tt := range cases {
+		c := &Client{URL: srv.URL, Token: tt.token}
+		if err := c.send(tt.method, tt.path, nil, "", nil); (err != nil) != tt.wantErr {
+			t.Fatalf("[%d] want error %v for %s %s with %s, got %v\n", i, tt.wantErr, tt.method, tt.path, tt.token, err)
+		}
+	}
+
+	probecfg := probeConfig{}
+	if err := (&Client{URL: srv.URL, Token: "eu-token"}).send("GET", "agent/probes", nil, "", &probecfg); err != nil {
+		t.Fatalf("failed to fetch agent probes: %v\n", err)
+	}
+	if len(probecfg.WebProbes) != 1 || probecfg.WebProbes[0].Name != "WebIndex" || probecfg.WebProbes[0].Escalation != "" {
+		t.Fatalf("want only WebIndex without its escalation, got %+v\n", probecfg.WebProbes)
+	}
+}
@@ -0,0 +1,192 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+// alertmanagerTimeout is how long to wait for Alertmanager to respond.
+const alertmanagerTimeout = time.Second * 10
+
+// errAlertmanager is returned when asked to acknowledge or silence
+// alerts that are posted to Alertmanager, which wouldn't stop them.
+var errAlertmanager = errors.New("acks and silences are handled by Alertmanager when DASHBOARD_ALERTMANAGER_URL is set")
+
+// resolver is a notifier told about each alert once, rather than per
+// target, on each failing run while it fires and once it's resolved.
+// It suppresses acknowledged and silenced alerts itself.
+type resolver interface {
+	// Fire posts the alert as still firing.
+	Fire(n notification) error
+	// Resolve posts the alert as resolved at the time.
+	Resolve(n notification, ended time.Time) error
+}
+
+// alertmanagerNotifier posts alerts to the Alertmanager v2 API, which
+// routes them instead of the escalation policies' targets.
+type alertmanagerNotifier struct {
+	endpoint string
+	client   *http.Client
+}
+
+// newAlertmanagerNotifier returns a notifier posting to the
+// Alertmanager at the base URL.
+func newAlertmanagerNotifier(baseURL string) alertmanagerNotifier {
+	return alertmanagerNotifier{
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/api/v2/alerts",
+		client:   &http.Client{Timeout: alertmanagerTimeout},
+	}
+}
+
+// amAlert is an alert in the Alertmanager v2 API.
+type amAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     string            `json:"startsAt,omitempty"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// getAlert returns the Alertmanager alert for the notification, ending
+// at the time unless it's zero.
+func getAlert(n notification, ended time.Time) amAlert {
+	labels := map[string]string{}
+	for k, v := range n.Labels {
+		labels[k] = v
+	}
+	labels["alertname"] = n.Name
+	labels["probe"] = n.Name
+	labels["kind"] = n.Kind
+	a := amAlert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":     n.Name + " is alerting",
+			"description": n.Desc,
+			"info":        n.Info,
+		},
+		GeneratorURL: n.URL,
+	}
+	if !n.Since.IsZero() {
+		a.StartsAt = n.Since.Format(time.RFC3339)
+	}
+	if !ended.IsZero() {
+		a.EndsAt = ended.Format(time.RFC3339)
+	}
+	return a
+}
+
+// post sends the alerts to Alertmanager.
+func (a alertmanagerNotifier) post(alerts ...amAlert) error {
+	b, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Post(a.endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alertmanager responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Notify posts the firing alert, whoever the target is.
+func (a alertmanagerNotifier) Notify(to string, n notification) error {
+	return a.Fire(n)
+}
+
+// Fire posts the firing alert. Without an end time, Alertmanager
+// resolves it unless it's posted again within its resolve timeout.
+func (a alertmanagerNotifier) Fire(n notification) error {
+	return a.post(getAlert(n, time.Time{}))
+}
+
+// Resolve posts the alert as resolved at the time.
+func (a alertmanagerNotifier) Resolve(n notification, ended time.Time) error {
+	return a.post(getAlert(n, ended))
+}
+
+// getNotification returns the notification about the probe's alert.
+func (d *Dashboard) getNotification(name, desc string, badness int, info probeInfo) notification {
+	n := notification{
+		Name:    name,
+		Desc:    desc,
+		Badness: badness,
+		Kind:    info.Kind,
+		Labels:  info.Labels,
+		Links:   info.Links,
+		Since:   d.alerts.get(name).Since,
+	}
+	if rs, _ := d.history.results(name, 0, 1); len(rs) > 0 {
+		n.Info = rs[0].Info
+	}
+	if d.conf.ExternalURL != "" {
+		n.URL = d.conf.ExternalURL + d.conf.HttpPrefix + "/probes/" + url.PathEscape(name)
+	}
+	return n
+}
+
+// fireAlert posts the probe's alert to the resolver as still firing,
+// even if it's acknowledged or silenced here, noting the first post in
+// the incident timeline.
+func (d *Dashboard) fireAlert(r resolver, name, desc string, badness int, records prober.Records, raised bool) error {
+	d.sending.RLock()
+	defer d.sending.RUnlock()
+	if d.notifyStopped || (d.lease != nil && !d.leader) {
+		return nil
+	}
+	n := d.getNotification(name, desc, badness, d.getProbeInfo(name))
+	n.Records = records
+	err := r.Fire(n)
+	d.notified(err)
+	if err != nil {
+		return err
+	}
+	if raised {
+		d.incidents.record(incidentEvent{Kind: "notified", Probe: name, Text: "Posted to Alertmanager"})
+	}
+	return nil
+}
+
+// clearAlert clears the alert of the probe, as configured by the info,
+// telling the notifier it's resolved. It returns false if there was
+// no alert.
+func (d *Dashboard) clearAlert(name string, info probeInfo) bool {
+	a := d.alerts.get(name)
+	if !d.alerts.clear(name) {
+		return false
+	}
+	if d.isLeader() {
+		if err := d.shareState(nil); err != nil {
+			log.Printf("Failed to share recovery of %s: %v\n", name, err)
+		}
+	}
+	r, ok := d.notifier.(resolver)
+	if !ok {
+		return true
+	}
+	d.sending.RLock()
+	defer d.sending.RUnlock()
+	if d.notifyStopped || d.conf.ProberDisabled || (d.lease != nil && !d.leader) {
+		return true
+	}
+	n := d.getNotification(name, "", 0, info)
+	n.Since = a.Since
+	err := r.Resolve(n, time.Now())
+	d.notified(err)
+	if err != nil {
+		log.Printf("Failed to resolve alert for %s: %v\n", name, err)
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestAlertmanager(t *testing.T) {
+	lock := sync.Mutex{}
+	posted := []amAlert{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v2/alerts" {
+			http.NotFound(w, r)
+			return
+		}
+		alerts := []amAlert{}
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		posted = append(posted, alerts...)
+	}))
+	defer srv.Close()
+
+	d := newTestDashboard(t, Config{Debug: true, AlertmanagerURL: srv.URL + "/", ExternalURL: "https://gomon.example.com"})
+	if _, ok := d.notifier.(alertmanagerNotifier); !ok {
+		t.Fatalf("want alertmanager notifier, got %T\n", d.notifier)
+	}
+	p := prober.NewProbe(fakeProber{}, "Custom", "Custom check")
+	if err := d.AddProbe(p, Labels(map[string]string{"team": "ops"})); err != nil {
+		t.Fatalf("failed to add probe: %v\n", err)
+	}
+	d.policies[defaultPolicy] = escalationPolicy{Name: defaultPolicy, Tiers: []escalationTier{{Targets: []string{"ops@example.com", "dev@example.com"}}}}
+	d.history.record("Custom", prober.Result{Info: "connection refused"}, time.Now(), time.Second)
+	if err := d.sendAlert("Custom", "Custom check", 100, nil); err != nil {
+		t.Fatalf("failed to send alert: %v\n", err)
+	}
+	if err := d.acknowledge("Custom", "alice"); err != errAlertmanager || d.alerts.get("Custom").Acked() {
+		t.Fatalf("want acknowledging refused with Alertmanager, got %v\n", err)
+	}
+	if _, err := d.addSilence("Cust*", "alice", "deploying", time.Hour); err != errAlertmanager {
+		t.Fatalf("want silencing refused with Alertmanager, got %v\n", err)
+	}
+	if err := d.sendAlert("Custom", "Custom check", 100, nil); err != nil {
+		t.Fatalf("failed to send alert: %v\n", err)
+	}
+	if err := d.RemoveProbe("Custom"); err != nil {
+		t.Fatalf("failed to remove probe: %v\n", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	cases := []struct {
+		resolved bool
+	}{
+		{false},
+		{false},
+		{true},
+	}
+	if len(posted) != len(cases) {
+		t.Fatalf("want alert posted once per failing run and resolved, got %+v\n", posted)
+	}
+	for i, tt := range cases {
+		a := posted[i]
+		if a.Labels["alertname"] != "Custom" || a.Labels["kind"] != "custom" || a.Labels["team"] != "ops" {
+			t.Fatalf("[%d] want labels of Custom, got %v\n", i, a.Labels)
+		}
+		if a.Annotations["info"] != "connection refused" {
+			t.Fatalf("[%d] want info of latest result, got %v\n", i, a.Annotations)
+		}
+		if a.StartsAt == "" || a.GeneratorURL != "https://gomon.example.com/probes/Custom" {
+			t.Fatalf("[%d] want startsAt and generatorURL, got %+v\n", i, a)
+		}
+		if got := a.EndsAt != ""; got != tt.resolved {
+			t.Fatalf("[%d] want resolved %v, got endsAt %q\n", i, tt.resolved, a.EndsAt)
+		}
+	}
+	if posted[0].Annotations["description"] != "Custom check" {
+		t.Fatalf("want description of Custom, got %v\n", posted[0].Annotations)
+	}
+}
+
+func TestAlertmanagerQuorum(t *testing.T) {
+	lock := sync.Mutex{}
+	posts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		posts++
+	}))
+	defer srv.Close()
+
+	d := newTestDashboard(t, Config{Debug: true, AlertmanagerURL: srv.URL})
+	p := prober.NewProbe(fakeProber{}, "WebIndex", "")
+	d.AddProbe(p, Locations(2, "eu", "us"))
+	for _, loc := range []string{"eu", "us"} {
+		d.history.record(locationKey("WebIndex", loc), prober.Result{Info: "down"}, time.Now(), 0)
+	}
+	for i := 1; i <= 3; i++ {
+		d.checkQuorum(p)
+		lock.Lock()
+		got := posts
+		lock.Unlock()
+		if got != i {
+			t.Fatalf("[%d] want firing alert posted on each quorum check, got %d posts\n", i, got)
+		}
+	}
+}
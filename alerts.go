@@ -0,0 +1,297 @@
+package dashboard
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+// ackLinkTTL is how long signed acknowledgement links stay valid.
+const ackLinkTTL = time.Hour * 24
+
+// alertState describes the alert raised for a probe, if any.
+type alertState struct {
+	Alerting bool      // whether the probe is alerting
+	Since    time.Time // when the alert was raised
+	AckedBy  string    // who acknowledged the alert, if anyone
+	AckedAt  time.Time // when the alert was acknowledged
+	// Escalations are the tiers of the escalation policy notified
+	// about the alert so far.
+	Escalations []escalation
+	// Desc, Badness and Records are of the probe as it last alerted,
+	// for escalating without reading the probe while it runs.
+	Desc    string
+	Badness int
+	Records prober.Records
+}
+
+// Acked returns true if the alert has been acknowledged.
+func (a alertState) Acked() bool { return a.AckedBy != "" }
+
+// alertBook tracks the alert state of all probes.
+type alertBook struct {
+	sync.Mutex
+	states map[string]*alertState
+}
+
+// newAlertBook returns a new alert book, with no probes alerting.
+func newAlertBook() *alertBook {
+	return &alertBook{states: map[string]*alertState{}}
+}
+
+// get returns the alert state for the probe.
+func (b *alertBook) get(name string) alertState {
+	b.Lock()
+	defer b.Unlock()
+	if a, ok := b.states[name]; ok {
+		return *a
+	}
+	return alertState{}
+}
+
+// raise marks the probe as alerting, returning its alert state and
+// whether it was newly raised.
+func (b *alertBook) raise(name string) (alertState, bool) {
+	b.Lock()
+	defer b.Unlock()
+	a, ok := b.states[name]
+	if ok && a.Alerting {
+		return *a, false
+	}
+	a = &alertState{Alerting: true, Since: time.Now()}
+	b.states[name] = a
+	return *a, true
+}
+
+// update records the probe as it alerted, copying the records.
+func (b *alertBook) update(name, desc string, badness int, records prober.Records) {
+	rs := prober.Records{}
+	for _, r := range records {
+		c := *r
+		rs = append(rs, &c)
+	}
+	b.Lock()
+	defer b.Unlock()
+	if a, ok := b.states[name]; ok && a.Alerting {
+		a.Desc, a.Badness, a.Records = desc, badness, rs
+	}
+}
+
+// escalated records that a tier was notified about the probe's alert.
+func (b *alertBook) escalated(name string, e escalation) {
+	b.Lock()
+	defer b.Unlock()
+	if a, ok := b.states[name]; ok && a.Alerting {
+		a.Escalations = append(a.Escalations, e)
+	}
+}
+
+// clear marks the probe as no longer alerting, dropping any
+// acknowledgement. clear returns true if the probe was alerting.
+func (b *alertBook) clear(name string) bool {
+	b.Lock()
+	defer b.Unlock()
+	a, ok := b.states[name]
+	if !ok || !a.Alerting {
+		return false
+	}
+	delete(b.states, name)
+	return true
+}
+
+// sync merges the acknowledgements and escalations of alerts with the
+// ones shared by other dashboards, returning the alerts newly
+// acknowledged elsewhere. Only the leader drops shared alerts once the
+// probe is no longer alerting.
+func (b *alertBook) sync(shared map[string]sharedAlert, leader bool) []string {
+	b.Lock()
+	defer b.Unlock()
+	acked := []string{}
+	for name, a := range b.states {
+		if !a.Alerting {
+			continue
+		}
+		s := shared[name]
+		if s.AckedBy != "" && s.AckedBy != a.AckedBy {
+			a.AckedBy, a.AckedAt = s.AckedBy, s.AckedAt
+			log.Printf("Alert for %q acknowledged by %s on another instance\n", name, a.AckedBy)
+			acked = append(acked, name)
+		} else {
+			s.AckedBy, s.AckedAt = a.AckedBy, a.AckedAt
+		}
+		if len(s.Escalations) > len(a.Escalations) {
+			a.Escalations = append([]escalation{}, s.Escalations...)
+		} else {
+			s.Escalations = append([]escalation{}, a.Escalations...)
+		}
+		shared[name] = s
+	}
+	if leader {
+		for name := range shared {
+			if a, ok := b.states[name]; !ok || !a.Alerting {
+				delete(shared, name)
+			}
+		}
+	}
+	return acked
+}
+
+// ack records that who is handling the alert for the probe.
+func (b *alertBook) ack(name, who string) error {
+	if who == "" {
+		return errors.New("no name given for acknowledgement")
+	}
+	b.Lock()
+	defer b.Unlock()
+	a, ok := b.states[name]
+	if !ok || !a.Alerting {
+		return fmt.Errorf("probe %q is not alerting", name)
+	}
+	if a.Acked() {
+		return fmt.Errorf("alert for %q was already acknowledged by %s", name, a.AckedBy)
+	}
+	a.AckedBy = who
+	a.AckedAt = time.Now()
+	log.Printf("Alert for %q acknowledged by %s\n", name, who)
+	return nil
+}
+
+// acknowledge records that who is handling the alert for the probe,
+// adding it to the incident timeline. Alerts posted to Alertmanager
+// must be handled there instead.
+func (d *Dashboard) acknowledge(probe, who string) error {
+	if _, ok := d.notifier.(resolver); ok {
+		return errAlertmanager
+	}
+	if err := d.alerts.ack(probe, who); err != nil {
+		return err
+	}
+	d.incidents.record(incidentEvent{Kind: "ack", Probe: probe, By: who, Text: "Alert acknowledged"})
+	d.publishAlert(probe)
+	if err := d.shareState(nil); err != nil {
+		log.Printf("Failed to share acknowledgement of %s: %v\n", probe, err)
+	}
+	return nil
+}
+
+// getAckSecret returns the key for signing acknowledgement links,
+// generating a random one if none is given.
+func getAckSecret(secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	log.Printf("No DASHBOARD_ACKSECRET specified, acknowledgement links won't survive restarts\n")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ackSig returns the signature allowing who to acknowledge the alert
+// for the probe until the expiry time. Fields are prefixed by their
+// length, so no two of them sign the same message.
+func (d *Dashboard) ackSig(probe, who string, expires int64) string {
+	mac := hmac.New(sha256.New, d.ackSecret)
+	fmt.Fprintf(mac, "%d:%s%d:%s%d", len(probe), probe, len(who), who, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getAckURL returns a signed link acknowledging the alert for the
+// probe on behalf of who, or "" if no external URL is configured.
+func (d *Dashboard) getAckURL(baseURL, probe, who string) string {
+	if baseURL == "" {
+		return ""
+	}
+	expires := time.Now().Add(ackLinkTTL).Unix()
+	v := url.Values{}
+	v.Set("probe", probe)
+	v.Set("by", who)
+	v.Set("expires", strconv.FormatInt(expires, 10))
+	v.Set("sig", d.ackSig(probe, who, expires))
+	return fmt.Sprintf("%s%s/ack?%s", baseURL, d.conf.HttpPrefix, v.Encode())
+}
+
+// ackLink is a signed link acknowledging an alert.
+type ackLink struct {
+	Probe, By, Expires, Sig string
+}
+
+// checkAckLink returns the signed link in the request, or an error and
+// the HTTP status to respond with if it's bad or has expired.
+func (d *Dashboard) checkAckLink(r *http.Request) (ackLink, int, error) {
+	l := ackLink{r.FormValue("probe"), r.FormValue("by"), r.FormValue("expires"), r.FormValue("sig")}
+	expires, err := strconv.ParseInt(l.Expires, 10, 64)
+	if err != nil {
+		return l, http.StatusBadRequest, errors.New("Bad acknowledgement link.")
+	}
+	want := d.ackSig(l.Probe, l.By, expires)
+	if !hmac.Equal([]byte(want), []byte(l.Sig)) {
+		return l, http.StatusForbidden, errors.New("Bad acknowledgement link.")
+	}
+	if time.Now().Unix() > expires {
+		return l, http.StatusForbidden, errors.New("Acknowledgement link has expired.")
+	}
+	return l, http.StatusOK, nil
+}
+
+// confirmAckLink serves a page confirming the acknowledgement from a
+// signed link, which posts it to ackFromLink. Following the link
+// doesn't acknowledge the alert itself, so mail scanners and
+// prefetchers don't.
+func (d *Dashboard) confirmAckLink(w http.ResponseWriter, r *http.Request) {
+	l, code, err := d.checkAckLink(r)
+	if err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	tmpl, err := d.getTemplate(ackTmpls)
+	if err != nil {
+		log.Printf("error parsing templates: %v\n", err)
+		serveISE(w)
+		return
+	}
+	if err := tmpl.ExecuteTemplate(w, baseTemplate, l); err != nil {
+		log.Printf("error rendering template: %v\n", err)
+		serveISE(w)
+	}
+}
+
+// ackFromLink acknowledges an alert as confirmed from a signed link.
+func (d *Dashboard) ackFromLink(w http.ResponseWriter, r *http.Request) {
+	l, code, err := d.checkAckLink(r)
+	if err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	if err := d.acknowledge(l.Probe, l.By); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	fmt.Fprintf(w, "Alert for %s acknowledged by %s.\n", l.Probe, l.By)
+}
+
+// ackFromForm acknowledges an alert from the form on the index page,
+// on behalf of the viewer.
+func (d *Dashboard) ackFromForm(w http.ResponseWriter, r *http.Request) {
+	probe := r.FormValue("probe")
+	if err := d.acknowledge(probe, d.getViewer(r)); err == errAlertmanager {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, d.conf.HttpPrefix+"/#"+probe, http.StatusSeeOther)
+}
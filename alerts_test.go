@@ -0,0 +1,123 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAckFromLink(t *testing.T) {
+	cases := []struct {
+		probe, by string
+		expires   time.Time
+		sigFor    string // who the link was signed for
+		alerting  bool
+		wantCode  int
+	}{
+		{"WebIndex", "ops@example.com", time.Now().Add(time.Hour), "ops@example.com", true, http.StatusOK},
+		{"WebIndex", "ops@example.com", time.Now().Add(time.Hour), "other@example.com", true, http.StatusForbidden},
+		{"WebIndex", "ops@example.com", time.Now().Add(-time.Hour), "ops@example.com", true, http.StatusForbidden},
+		{"WebIndex", "ops@example.com", time.Now().Add(time.Hour), "ops@example.com", false, http.StatusConflict},
+	}
+	for i, tt := range cases {
+		d := newTestDashboard(t, Config{Debug: true, AckSecret: "secret"})
+		if tt.alerting {
+			d.alerts.raise(tt.probe)
+			d.incidents.probeAlerting(tt.probe)
+		}
+		v := url.Values{}
+		v.Set("probe", tt.probe)
+		v.Set("by", tt.by)
+		v.Set("expires", strconv.FormatInt(tt.expires.Unix(), 10))
+		v.Set("sig", d.ackSig(tt.probe, tt.sigFor, tt.expires.Unix()))
+		req, err := http.NewRequest("GET", "/ack?"+v.Encode(), nil)
+		if err != nil {
+			t.Fatalf("[%d] failed to create request: %v\n", i, err)
+		}
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, req)
+		if got := d.alerts.get(tt.probe); got.Acked() {
+			t.Fatalf("[%d] want alert not acked by following link, got %+v\n", i, got)
+		}
+		if tt.wantCode == http.StatusOK && !strings.Contains(w.Body.String(), v.Get("sig")) {
+			t.Fatalf("[%d] want confirmation form with signature, got %d: %s\n", i, w.Code, w.Body.String())
+		}
+
+		req, err = http.NewRequest("POST", "/ack/link", strings.NewReader(v.Encode()))
+		if err != nil {
+			t.Fatalf("[%d] failed to create request: %v\n", i, err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w = httptest.NewRecorder()
+		d.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("[%d] want HTTP response %d, got %d: %s\n", i, tt.wantCode, w.Code, w.Body.String())
+		}
+		if got := d.alerts.get(tt.probe); tt.wantCode == http.StatusOK && got.AckedBy != tt.by {
+			t.Fatalf("[%d] want alert acked by %q, got %+v\n", i, tt.by, got)
+		}
+	}
+}
+
+func TestAckFromForm(t *testing.T) {
+	cases := []struct {
+		roles    string
+		csrfUser string // whose CSRF token the form sends, if any
+		wantCode int
+		wantBy   string
+	}{
+		{"", "alice", http.StatusForbidden, ""},
+		{"viewer", "alice", http.StatusForbidden, ""},
+		{"oncall", "", http.StatusForbidden, ""},
+		{"oncall", "mallory", http.StatusForbidden, ""},
+		{"oncall", "alice", http.StatusSeeOther, "alice"},
+	}
+	for i, tt := range cases {
+		d := newTestDashboard(t, Config{Debug: true, RolesHeader: "X-Roles", UserHeader: "X-User", ActionRoles: []string{"oncall"}})
+		d.alerts.raise("WebIndex")
+		d.incidents.probeAlerting("WebIndex")
+		form := url.Values{"probe": {"WebIndex"}, "by": {"mallory"}}
+		if tt.csrfUser != "" {
+			form.Set("csrf", csrfFor(d, tt.csrfUser))
+		}
+		req, err := http.NewRequest("POST", "/ack", strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatalf("[%d] failed to create request: %v\n", i, err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Roles", tt.roles)
+		req.Header.Set("X-User", "alice")
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Fatalf("[%d] want HTTP response %d, got %d: %s\n", i, tt.wantCode, w.Code, w.Body.String())
+		}
+		if got := d.alerts.get("WebIndex"); got.AckedBy != tt.wantBy {
+			t.Fatalf("[%d] want alert acked by %q, got %+v\n", i, tt.wantBy, got)
+		}
+	}
+}
+
+func TestAckSig(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true, AckSecret: "secret"})
+	cases := []struct {
+		probe, by string
+	}{
+		{"Web\nIndex", "ops@example.com"},
+		{"Web", "Index\nops@example.com"},
+		{"WebIndex", "ops@example.com"},
+	}
+	sigs := map[string]int{}
+	for i, tt := range cases {
+		sig := d.ackSig(tt.probe, tt.by, 1700000000)
+		if j, ok := sigs[sig]; ok {
+			t.Fatalf("[%d] want distinct signature for %q by %q, got the same as [%d]\n", i, tt.probe, tt.by, j)
+		}
+		sigs[sig] = i
+	}
+}
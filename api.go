@@ -0,0 +1,416 @@
+package dashboard
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"hkjn.me/prober"
+
+	"hkjn.me/dashboard/gen"
+)
+
+// maxAPIResults is the most results returned by the API at once.
+const maxAPIResults = 500
+
+// Status summarizes the state of a dashboard, as served by the API.
+type Status struct {
+	Version       string    `json:"version"`
+	StartTime     time.Time `json:"startTime"`
+	Probes        int       `json:"probes"`
+	Failing       int       `json:"failing"`  // probes whose last run failed
+	Alerting      int       `json:"alerting"` // probes with a raised alert
+	Silenced      int       `json:"silenced"` // probes with silenced alerts
+	OpenIncidents int       `json:"openIncidents"`
+	Leader        bool      `json:"leader"` // whether this instance sends notifications
+}
+
+// ProbeStatus is the state of a probe, as served by the API.
+type ProbeStatus struct {
+	Name     string            `json:"name"`
+	Desc     string            `json:"desc"`
+	Kind     string            `json:"kind"`
+	Target   string            `json:"target"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Badness  int               `json:"badness"`
+	Disabled bool              `json:"disabled"`
+	Alerting bool              `json:"alerting"`
+	AckedBy  string            `json:"ackedBy,omitempty"`
+	Silenced bool              `json:"silenced"`
+	Pause    *Pause            `json:"pause,omitempty"` // why the probe is paused, if it is
+	Last     *ProbeResult      `json:"last,omitempty"`  // the latest result, if any
+}
+
+// Failing returns true if the latest run of the probe failed.
+func (p ProbeStatus) Failing() bool { return p.Last != nil && !p.Last.Passed }
+
+// matches returns true if the probe is in the state, which is one of
+// "alerting", "failing", "passing", "silenced" and "disabled", or "" to
+// match any probe.
+func (p ProbeStatus) matches(state string) bool {
+	switch state {
+	case "":
+		return true
+	case "alerting":
+		return p.Alerting
+	case "failing":
+		return p.Failing()
+	case "passing":
+		return p.Last != nil && p.Last.Passed
+	case "silenced":
+		return p.Silenced
+	case "disabled":
+		return p.Disabled
+	}
+	return false
+}
+
+// hasLabels returns true if the probe has all the labels, given as
+// "key=value".
+func (p ProbeStatus) hasLabels(labels []string) bool {
+	for _, l := range labels {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 || p.Labels[kv[0]] != kv[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// getProbeStatus returns the state of the probe.
+func (d *Dashboard) getProbeStatus(p *prober.Probe) ProbeStatus {
+	info := d.getProbeInfo(p.Name)
+	a := d.alerts.get(p.Name)
+	_, silenced := d.silences.silenced(p.Name)
+	s := ProbeStatus{
+		Name:     p.Name,
+		Desc:     p.Desc,
+		Kind:     info.Kind,
+		Target:   info.Target,
+		Labels:   info.Labels,
+		Badness:  p.Badness,
+		Disabled: p.Disabled,
+		Alerting: a.Alerting,
+		AckedBy:  a.AckedBy,
+		Silenced: silenced,
+	}
+	if pause, ok := d.pauses.get(p.Name); ok {
+		s.Pause, s.Disabled = &pause, true
+	}
+	if rs, _ := d.history.results(p.Name, 0, 1); len(rs) > 0 {
+		r := toProbeResult(p.Name, rs[0])
+		s.Last = &r
+	}
+	return s
+}
+
+// toProbeResult returns the result from the history as served by the
+// API.
+func toProbeResult(probe string, r result) ProbeResult {
+	return ProbeResult{probe, r.Time, r.Passed, r.Info, r.Latency}
+}
+
+// withToken returns a handler requiring an API token, which calls h
+// with the name of who the token belongs to.
+func (d *Dashboard) withToken(h func(w http.ResponseWriter, r *http.Request, who string)) http.HandlerFunc {
+	return requireToken(d.conf.APITokens, "DASHBOARD_API_TOKENS", h)
+}
+
+// withAgentToken returns a handler requiring an agent token, which
+// calls h with the location the token is bound to.
+func (d *Dashboard) withAgentToken(h func(w http.ResponseWriter, r *http.Request, location string)) http.HandlerFunc {
+	return requireToken(d.conf.AgentTokens, "DASHBOARD_AGENT_TOKENS", h)
+}
+
+// requireToken returns a handler requiring one of the tokens, set in
+// the variable, which calls h with the name the token is under.
+func requireToken(tokens map[string]string, variable string, h func(w http.ResponseWriter, r *http.Request, who string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(tokens) == 0 {
+			http.Error(w, fmt.Sprintf("API is disabled, as no %s are set.", variable), http.StatusForbidden)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		for who, t := range tokens {
+			if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				h(w, r, who)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Bad or missing API token.", http.StatusUnauthorized)
+	}
+}
+
+// serveJSON writes the value as JSON.
+func serveJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode API response: %v\n", err)
+	}
+}
+
+// apiStatus serves the status of the dashboard.
+func (d *Dashboard) apiStatus(w http.ResponseWriter, r *http.Request, who string) {
+	d.probeLock.RLock()
+	s := Status{Version: gen.Version, StartTime: d.startTime}
+	d.probeLock.RUnlock()
+	s.Leader = d.isLeader()
+	for _, p := range d.listProbes() {
+		ps := d.getProbeStatus(p)
+		s.Probes++
+		if ps.Failing() {
+			s.Failing++
+		}
+		if ps.Alerting {
+			s.Alerting++
+		}
+		if ps.Silenced {
+			s.Silenced++
+		}
+	}
+	for _, i := range d.incidents.list() {
+		if i.Open() {
+			s.OpenIncidents++
+		}
+	}
+	serveJSON(w, s)
+}
+
+// apiProbes serves the state of the probes matching the "state",
+// "label" and "name" parameters, if given.
+func (d *Dashboard) apiProbes(w http.ResponseWriter, r *http.Request, who string) {
+	r.ParseForm()
+	state := r.FormValue("state")
+	patterns := r.Form["name"]
+	probes := []ProbeStatus{}
+	for _, p := range d.listProbes() {
+		if !matchesAny(p.Name, patterns) {
+			continue
+		}
+		ps := d.getProbeStatus(p)
+		if ps.matches(state) && ps.hasLabels(r.Form["label"]) {
+			probes = append(probes, ps)
+		}
+	}
+	serveJSON(w, probes)
+}
+
+// apiProbe serves the state of a probe.
+func (d *Dashboard) apiProbe(w http.ResponseWriter, r *http.Request, who string) {
+	p := d.findProbe(getProbeName(r))
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	serveJSON(w, d.getProbeStatus(p))
+}
+
+// apiResults serves results of a probe, most recent first, after
+// skipping "offset" results and returning at most "limit".
+func (d *Dashboard) apiResults(w http.ResponseWriter, r *http.Request, who string) {
+	p := d.findProbe(getProbeName(r))
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	offset, _ := strconv.Atoi(r.FormValue("offset"))
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 || limit > maxAPIResults {
+		limit = resultsPerPage
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	rs, _ := d.history.results(p.Name, offset, limit)
+	results := make([]ProbeResult, len(rs))
+	for i, r := range rs {
+		results[i] = toProbeResult(p.Name, r)
+	}
+	serveJSON(w, results)
+}
+
+// apiAck acknowledges the alert of a probe, on behalf of "by" if given
+// or the token's owner otherwise.
+func (d *Dashboard) apiAck(w http.ResponseWriter, r *http.Request, who string) {
+	by := r.FormValue("by")
+	if by == "" {
+		by = who
+	}
+	name := getProbeName(r)
+	if d.findProbe(name) == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := d.acknowledge(name, by); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	serveJSON(w, d.alerts.get(name))
+}
+
+// apiRun runs a probe right away, serving its result.
+func (d *Dashboard) apiRun(w http.ResponseWriter, r *http.Request, who string) {
+	p := d.findProbe(getProbeName(r))
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	res, err := d.runNow(p, who)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	serveJSON(w, res)
+}
+
+// maxPushedResults is the most results accepted in a single push.
+const maxPushedResults = 1000
+
+// decodeJSON decodes the list of results pushed in the request into
+// the slice v points to, serving an error and returning false if
+// they're bad.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(v); err != nil {
+		http.Error(w, "Bad results: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if reflect.ValueOf(v).Elem().Len() > maxPushedResults {
+		http.Error(w, "Too many results.", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	return true
+}
+
+// apiPushResults records the results of probe runs made elsewhere,
+// given as a JSON list, as if the probes had run here.
+func (d *Dashboard) apiPushResults(w http.ResponseWriter, r *http.Request, who string) {
+	results := []ProbeResult{}
+	if !decodeJSON(w, r, &results) {
+		return
+	}
+	for _, res := range results {
+		if d.findProbe(res.Name) == nil {
+			http.Error(w, "No probe named "+res.Name+".", http.StatusBadRequest)
+			return
+		}
+	}
+	now := time.Now()
+	for _, res := range results {
+		if res.Time.IsZero() || res.Time.After(now) {
+			res.Time = now
+		}
+		pr := prober.Result{Passed: res.Passed, Info: res.Info}
+		d.history.record(res.Name, pr, res.Time, res.Duration)
+		d.publishResult(res.Name, pr, res.Time)
+	}
+	log.Printf("Recorded %d results pushed by %s\n", len(results), who)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiPause pauses a probe for "duration" because of "reason", on
+// behalf of "by" if given or the token's owner otherwise.
+func (d *Dashboard) apiPause(w http.ResponseWriter, r *http.Request, who string) {
+	by := r.FormValue("by")
+	if by == "" {
+		by = who
+	}
+	duration, err := time.ParseDuration(r.FormValue("duration"))
+	if err != nil {
+		http.Error(w, "Bad duration.", http.StatusBadRequest)
+		return
+	}
+	name := getProbeName(r)
+	err = d.pause(name, by, r.FormValue("reason"), duration)
+	if err == errNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	serveJSON(w, d.getProbeStatus(d.findProbe(name)))
+}
+
+// apiResume resumes a paused probe.
+func (d *Dashboard) apiResume(w http.ResponseWriter, r *http.Request, who string) {
+	name := getProbeName(r)
+	if d.findProbe(name) == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := d.resume(name, who); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	serveJSON(w, d.getProbeStatus(d.findProbe(name)))
+}
+
+// apiSilences serves the active silences, or all of them if "all" is
+// set.
+func (d *Dashboard) apiSilences(w http.ResponseWriter, r *http.Request, who string) {
+	all, _ := strconv.ParseBool(r.FormValue("all"))
+	serveJSON(w, d.silences.list(!all))
+}
+
+// apiAddSilence silences the probes matching "pattern" for "duration",
+// on behalf of "by" if given or the token's owner otherwise.
+func (d *Dashboard) apiAddSilence(w http.ResponseWriter, r *http.Request, who string) {
+	by := r.FormValue("by")
+	if by == "" {
+		by = who
+	}
+	duration, err := time.ParseDuration(r.FormValue("duration"))
+	if err != nil {
+		http.Error(w, "Bad duration.", http.StatusBadRequest)
+		return
+	}
+	s, err := d.addSilence(r.FormValue("pattern"), by, r.FormValue("comment"), duration)
+	if err == errAlertmanager {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	serveJSON(w, s)
+}
+
+// apiExpireSilence ends a silence now.
+func (d *Dashboard) apiExpireSilence(w http.ResponseWriter, r *http.Request, who string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = d.expireSilence(id)
+	if err == errNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("Silence %d expired by %s\n", id, who)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiReload reloads the probes config.
+func (d *Dashboard) apiReload(w http.ResponseWriter, r *http.Request, who string) {
+	log.Printf("Reloading probes config at the request of %s\n", who)
+	if err := d.Reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package dashboard
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestAPI(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true, APITokens: map[string]string{"ops": "secret"}})
+	d.AddProbe(prober.NewProbe(resultProber{Passed: true}, "WebIndex", ""), Labels(map[string]string{"team": "web"}))
+	d.AddProbe(prober.NewProbe(resultProber{Info: "down"}, "DnsRoot", ""))
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	c := &Client{URL: srv.URL, Token: "secret"}
+
+	if _, err := (&Client{URL: srv.URL, Token: "wrong"}).Status(); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("want 401 error with bad token, got %v\n", err)
+	}
+	if r, err := c.Run("DnsRoot"); err != nil || r.Passed || r.Info != "down" {
+		t.Fatalf("want failed run of DnsRoot, got %+v, %v\n", r, err)
+	}
+	cases := []struct {
+		filter ProbeFilter
+		want   []string
+	}{
+		{ProbeFilter{}, []string{"DnsRoot", "WebIndex"}},
+		{ProbeFilter{State: "failing"}, []string{"DnsRoot"}},
+		{ProbeFilter{Labels: []string{"team=web"}}, []string{"WebIndex"}},
+		{ProbeFilter{Patterns: []string{"Dns*"}}, []string{"DnsRoot"}},
+	}
+	for i, tt := range cases {
+		probes, err := c.Probes(tt.filter)
+		if err != nil {
+			t.Fatalf("[%d] failed to list probes: %v\n", i, err)
+		}
+		got := []string{}
+		for _, p := range probes {
+			got = append(got, p.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Fatalf("[%d] want probes %v, got %v\n", i, tt.want, got)
+		}
+	}
+
+	d.alerts.raise("DnsRoot")
+	if err := c.Ack("DnsRoot", ""); err != nil {
+		t.Fatalf("failed to ack: %v\n", err)
+	}
+	if got := d.alerts.get("DnsRoot"); got.AckedBy != "ops" {
+		t.Fatalf("want alert acked by token owner ops, got %+v\n", got)
+	}
+	s, err := c.AddSilence("Web*", "deploying", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to add silence: %v\n", err)
+	}
+	if p, err := c.Probe("WebIndex"); err != nil || !p.Silenced {
+		t.Fatalf("want WebIndex silenced, got %+v, %v\n", p, err)
+	}
+	if err := c.ExpireSilence(s.ID); err != nil {
+		t.Fatalf("failed to expire silence: %v\n", err)
+	}
+	if ss, err := c.Silences(false); err != nil || len(ss) != 0 {
+		t.Fatalf("want no active silences, got %+v, %v\n", ss, err)
+	}
+	status, err := c.Status()
+	if err != nil || status.Probes != 2 || status.Failing != 1 || status.Alerting != 1 {
+		t.Fatalf("want status with 2 probes, 1 failing and 1 alerting, got %+v, %v\n", status, err)
+	}
+
+	d.AddProbe(prober.NewProbe(resultProber{Passed: true}, "web/index", ""))
+	if r, err := c.Run("web/index"); err != nil || !r.Passed {
+		t.Fatalf("want run of web/index, got %+v, %v\n", r, err)
+	}
+	if p, err := c.Probe("web/index"); err != nil || p.Name != "web/index" {
+		t.Fatalf("want probe web/index, got %+v, %v\n", p, err)
+	}
+}
+
+func TestPassive(t *testing.T) {
+	// No SendGrid credentials are needed, as no alerts are sent.
+	d := newTestDashboard(t, Config{ProberDisabled: true, APITokens: map[string]string{"primary": "secret"}})
+	d.AddProbe(prober.NewProbe(resultProber{Info: "down"}, "WebIndex", ""))
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	c := &Client{URL: srv.URL, Token: "secret"}
+
+	if _, err := c.Run("WebIndex"); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Fatalf("want 409 error running probe of passive dashboard, got %v\n", err)
+	}
+	if err := c.PushResults([]ProbeResult{{Name: "Missing", Passed: true}}); err == nil {
+		t.Fatalf("want error pushing result of missing probe, got none\n")
+	}
+	pushed := ProbeResult{Name: "WebIndex", Time: time.Now().Add(-time.Minute), Info: "timeout", Duration: time.Second}
+	if err := c.PushResults([]ProbeResult{pushed}); err != nil {
+		t.Fatalf("failed to push results: %v\n", err)
+	}
+	if rs, err := c.Results("WebIndex", 0, 10); err != nil || len(rs) != 1 || rs[0].Info != "timeout" {
+		t.Fatalf("want pushed result of WebIndex, got %+v, %v\n", rs, err)
+	}
+	if rs := d.view(d.findProbe("WebIndex"), httptest.NewRequest("GET", "/", nil)).Records; len(rs) != 1 || rs[0].Result.Passed {
+		t.Fatalf("want pushed result on index page, got %+v\n", rs)
+	}
+	if err := d.sendAlert("WebIndex", "", 100, nil); err != nil || d.alerts.get("WebIndex").Alerting {
+		t.Fatalf("want no alert raised by passive dashboard, got %+v, %v\n", d.alerts.get("WebIndex"), err)
+	}
+}
@@ -0,0 +1,35 @@
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// embedded holds the templates, static files and default config built
+// into the binary.
+//
+//go:embed probes.yaml tmpl static
+var embedded embed.FS
+
+// ReadProbesConfig returns the probes.yaml a dashboard reads unless
+// given another: the one in the assets directory if set, otherwise the
+// one built into the binary.
+func ReadProbesConfig(assetsDir string) ([]byte, error) {
+	if assetsDir == "" {
+		return fs.ReadFile(embedded, "probes.yaml")
+	}
+	return fs.ReadFile(os.DirFS(assetsDir), "probes.yaml")
+}
+
+// getAssets returns where templates, static files and config are read
+// from: the directory if given, otherwise what's embedded in the
+// binary, and whether templates should be reloaded on each request.
+func getAssets(dir string) (fs.FS, bool) {
+	if dir == "" {
+		return embedded, false
+	}
+	log.Printf("Reading assets from %s, reloading templates on each request\n", dir)
+	return os.DirFS(dir), true
+}
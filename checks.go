@@ -0,0 +1,265 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+const (
+	// checkInterval is how often the latest result of external checks
+	// is sampled, as if they were probes running that often.
+	checkInterval = time.Minute
+	// defaultCheckStale is how long external checks may go without
+	// results by default before failing.
+	defaultCheckStale = time.Minute * 10
+	// maxExternalChecks is how many external checks may be added by
+	// pushing results, so a misbehaving client can't add unbounded
+	// probes.
+	maxExternalChecks = 100
+)
+
+// CheckResult is the result of an external check, as pushed to the
+// API.
+type CheckResult struct {
+	Name    string             `json:"name"`
+	Status  string             `json:"status"` // "ok", "warning", "critical" or "unknown"
+	Message string             `json:"message,omitempty"`
+	Time    time.Time          `json:"timestamp,omitempty"` // when the check ran, or now if unset
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// StaleAfter is how long the check may go without results before
+	// failing, like "15m", or 10 minutes if unset.
+	StaleAfter string `json:"staleAfter,omitempty"`
+}
+
+// passed returns true if the check passed, which it does with warnings.
+func (r CheckResult) passed() bool {
+	return r.Status == "ok" || r.Status == "warning"
+}
+
+// info describes the result, along with any metrics.
+func (r CheckResult) info() string {
+	info := r.Status
+	if r.Message != "" {
+		info += ": " + r.Message
+	}
+	if len(r.Metrics) > 0 {
+		ms := []string{}
+		for k, v := range r.Metrics {
+			ms = append(ms, fmt.Sprintf("%s=%g", k, v))
+		}
+		sort.Strings(ms)
+		info += " (" + strings.Join(ms, ", ") + ")"
+	}
+	return info
+}
+
+// result returns the probe result of the check's result.
+func (r CheckResult) result() prober.Result {
+	return prober.Result{Passed: r.passed(), Info: r.info()}
+}
+
+// validate returns an error if the result is malformed.
+func (r CheckResult) validate() error {
+	if r.Name == "" {
+		return errors.New("check has no name")
+	}
+	switch r.Status {
+	case "ok", "warning", "critical", "unknown":
+	default:
+		return fmt.Errorf("check %s has bad status %q, want ok, warning, critical or unknown", r.Name, r.Status)
+	}
+	if r.StaleAfter != "" {
+		if d, err := time.ParseDuration(r.StaleAfter); err != nil || d <= 0 {
+			return fmt.Errorf("check %s has bad staleAfter %q", r.Name, r.StaleAfter)
+		}
+	}
+	return nil
+}
+
+// externalProber is the prober of an external check, whose results
+// are pushed to the dashboard. Each run returns the latest result,
+// failing once results stop arriving.
+type externalProber struct {
+	sync.Mutex
+	last       CheckResult
+	received   time.Time // when the last result was checked
+	staleAfter time.Duration
+}
+
+// push records the latest result of the check, returning false if
+// it's no newer than the last one.
+func (p *externalProber) push(r CheckResult) bool {
+	p.Lock()
+	defer p.Unlock()
+	if !r.Time.After(p.received) {
+		return false
+	}
+	p.last, p.received = r, r.Time
+	p.staleAfter = defaultCheckStale
+	if d, err := time.ParseDuration(r.StaleAfter); err == nil {
+		p.staleAfter = d
+	}
+	return true
+}
+
+// isPushed returns true if the result of a run is the last one pushed,
+// which was recorded as it arrived.
+func (p *externalProber) isPushed(r prober.Result) bool {
+	p.Lock()
+	defer p.Unlock()
+	return !p.received.IsZero() && r == p.last.result()
+}
+
+// Probe returns the latest result of the check, or a failure if it's
+// stale.
+func (p *externalProber) Probe() prober.Result {
+	p.Lock()
+	defer p.Unlock()
+	if since := time.Since(p.received); since > p.staleAfter {
+		return prober.Result{Info: fmt.Sprintf("stale: no results for %v", since.Round(time.Second))}
+	}
+	return p.last.result()
+}
+
+// pushedProber is a prober whose results are pushed to the dashboard
+// and recorded as they arrive, rather than on each run.
+type pushedProber interface {
+	isPushed(r prober.Result) bool
+}
+
+// Alert does nothing, as alerts of tracked probes go through the
+// dashboard.
+func (p *externalProber) Alert(name, desc string, badness int, records prober.Records) error {
+	return nil
+}
+
+// kind sets the kind of probe added with AddProbe, instead of
+// "custom".
+func kind(k string) ProbeOption {
+	return func(r *probeRegistration) {
+		r.info.Kind = k
+	}
+}
+
+// countExternalChecks returns how many external checks there are.
+func (d *Dashboard) countExternalChecks() int {
+	d.probeLock.RLock()
+	defer d.probeLock.RUnlock()
+	n := 0
+	for _, info := range d.probeInfos {
+		if info.Kind == "external" {
+			n++
+		}
+	}
+	return n
+}
+
+// asExternalProber returns the prober of the probe, if it's an
+// external check.
+func asExternalProber(p *prober.Probe) (*externalProber, bool) {
+	tp, ok := p.Prober.(trackedProber)
+	if !ok {
+		return nil, false
+	}
+	ep, ok := tp.Prober.(*externalProber)
+	return ep, ok
+}
+
+// checkBatch returns an error unless all the results can be recorded:
+// probes of the same names must be external checks, and the new checks
+// mustn't make for more than maxExternalChecks.
+func (d *Dashboard) checkBatch(results []CheckResult) error {
+	added := map[string]bool{}
+	for _, res := range results {
+		p := d.findProbe(res.Name)
+		if p == nil {
+			added[res.Name] = true
+		} else if _, ok := asExternalProber(p); !ok {
+			return fmt.Errorf("probe %s isn't an external check", res.Name)
+		}
+	}
+	if n := d.countExternalChecks(); n+len(added) > maxExternalChecks {
+		return fmt.Errorf("can't add %d checks, there are already %d of at most %d external checks", len(added), n, maxExternalChecks)
+	}
+	return nil
+}
+
+// getExternalProber returns the prober of the external check, adding
+// it to the dashboard if it's new and there aren't too many.
+func (d *Dashboard) getExternalProber(name, who string) (*externalProber, error) {
+	if p := d.findProbe(name); p != nil {
+		ep, ok := asExternalProber(p)
+		if !ok {
+			return nil, fmt.Errorf("probe %s isn't an external check", name)
+		}
+		return ep, nil
+	}
+	if d.countExternalChecks() >= maxExternalChecks {
+		return nil, fmt.Errorf("can't add check %s, there are already %d external checks", name, maxExternalChecks)
+	}
+	ep := &externalProber{}
+	p := prober.NewProbe(ep, name, "External check pushed by "+who, prober.Interval(checkInterval))
+	if err := d.AddProbe(p, kind("external"), Target("pushed by "+who)); err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
+
+// apiChecks records results of external checks, given as a JSON list,
+// adding a probe for each new check. Results older than the latest one
+// of the check are dropped. Nothing is recorded unless the whole batch
+// can be.
+func (d *Dashboard) apiChecks(w http.ResponseWriter, r *http.Request, who string) {
+	results := []CheckResult{}
+	if !decodeJSON(w, r, &results) {
+		return
+	}
+	for _, res := range results {
+		if err := res.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	d.checkLock.Lock()
+	defer d.checkLock.Unlock()
+	if err := d.checkBatch(results); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	now := time.Now()
+	dropped := 0
+	for _, res := range results {
+		ep, err := d.getExternalProber(res.Name, who)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if res.Time.IsZero() || res.Time.After(now) {
+			res.Time = now
+		}
+		if rs, _ := d.history.results(res.Name, 0, 1); len(rs) > 0 && !res.Time.After(rs[0].Time) {
+			dropped++
+			continue
+		}
+		if !ep.push(res) {
+			dropped++
+			continue
+		}
+		info := d.getProbeInfo(res.Name)
+		run := probeRun{Name: res.Name, Info: info, Result: res.result(), Start: res.Time, Trace: d.startTrace()}
+		if p := d.findProbe(res.Name); p != nil {
+			run.Badness = p.Badness
+		}
+		d.recordRun(run)
+	}
+	log.Printf("Recorded %d external check results pushed by %s, dropping %d older ones\n", len(results)-dropped, who, dropped)
+	w.WriteHeader(http.StatusNoContent)
+}
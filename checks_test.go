@@ -0,0 +1,80 @@
+package dashboard
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestChecks(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true, APITokens: map[string]string{"backups": "secret"}})
+	d.AddProbe(prober.NewProbe(fakeProber{}, "WebIndex", ""))
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	c := &Client{URL: srv.URL, Token: "secret"}
+
+	if err := c.PushChecks([]CheckResult{{Name: "Backup", Status: "ok"}, {Name: "WebIndex", Status: "ok"}}); err == nil || d.findProbe("Backup") != nil {
+		t.Fatalf("want nothing recorded from batch with a non-external probe, got %v\n", err)
+	}
+
+	cases := []struct {
+		result  CheckResult
+		wantErr bool
+		want    prober.Result // result of the check's probe after the push
+	}{
+		{CheckResult{Name: "Backup", Status: "broken"}, true, prober.Result{}},
+		{CheckResult{Name: "WebIndex", Status: "ok"}, true, prober.Result{}},
+		{
+			CheckResult{Name: "Backup", Status: "ok", Message: "done", Metrics: map[string]float64{"size_gb": 1.5, "files": 12}},
+			false,
+			prober.Result{Passed: true, Info: "ok: done (files=12, size_gb=1.5)"},
+		},
+		{CheckResult{Name: "Backup", Status: "critical", Message: "disk full"}, false, prober.Result{Info: "critical: disk full"}},
+		{
+			CheckResult{Name: "Backup", Status: "ok", Time: time.Now().Add(-time.Hour)},
+			false,
+			prober.Result{Info: "critical: disk full"},
+		},
+		{
+			CheckResult{Name: "Cron", Status: "ok", Time: time.Now().Add(-time.Hour), StaleAfter: "30m"},
+			false,
+			prober.Result{Info: "stale: no results for 1h0m0s"},
+		},
+	}
+	for i, tt := range cases {
+		err := c.PushChecks([]CheckResult{tt.result})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("[%d] want error %v pushing %+v, got %v\n", i, tt.wantErr, tt.result, err)
+		}
+		if tt.wantErr {
+			continue
+		}
+		p := d.findProbe(tt.result.Name)
+		if p == nil || d.getProbeInfo(tt.result.Name).Kind != "external" {
+			t.Fatalf("[%d] want external probe %s, got %v\n", i, tt.result.Name, p)
+		}
+		if got := p.Prober.Probe(); got != tt.want {
+			t.Fatalf("[%d] want %+v, got %+v\n", i, tt.want, got)
+		}
+	}
+	if rs, _ := d.history.results("Backup", 0, 10); len(rs) != 2 || rs[0].Info != "critical: disk full" {
+		t.Fatalf("want the 2 results pushed for Backup in history, got %+v\n", rs)
+	}
+
+	results := []CheckResult{}
+	for i := d.countExternalChecks(); i < maxExternalChecks; i++ {
+		results = append(results, CheckResult{Name: fmt.Sprintf("Job%d", i), Status: "ok"})
+	}
+	if err := c.PushChecks(results); err != nil {
+		t.Fatalf("failed to push %d checks: %v\n", len(results), err)
+	}
+	if err := c.PushChecks([]CheckResult{{Name: "Backup", Status: "ok"}, {Name: "OneTooMany", Status: "ok"}}); err == nil || d.findProbe("OneTooMany") != nil {
+		t.Fatalf("want error adding more than %d checks, got %v\n", maxExternalChecks, err)
+	}
+	if rs, _ := d.history.results("Backup", 0, 10); len(rs) != 2 {
+		t.Fatalf("want nothing recorded for Backup from batch with too many checks, got %+v\n", rs)
+	}
+}
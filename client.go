@@ -0,0 +1,189 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to the API of a running dashboard.
+type Client struct {
+	// URL is the base URL of the dashboard, including any prefix.
+	URL string
+	// Token is the API token, as set in Config.APITokens.
+	Token string
+	// HTTPClient makes the requests, or http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// ProbeFilter selects probes to list.
+type ProbeFilter struct {
+	State    string   // "alerting", "failing", "passing", "silenced", "disabled", or "" for all
+	Labels   []string // labels the probes must all have, as "key=value"
+	Patterns []string // names of the probes, as for path.Match
+}
+
+// do sends a request for the API path with the parameters, decoding a
+// JSON response into v if it's not nil.
+func (c *Client) do(method, path string, params url.Values, v interface{}) error {
+	if method == "GET" {
+		if len(params) > 0 {
+			path += "?" + params.Encode()
+		}
+		return c.send(method, path, nil, "", v)
+	}
+	return c.send(method, path, strings.NewReader(params.Encode()), "application/x-www-form-urlencoded", v)
+}
+
+// send sends a request with the body of the content type for the API
+// path, decoding a JSON response into v if it's not nil.
+func (c *Client) send(method, path string, body io.Reader, contentType string, v interface{}) error {
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.URL, "/")+"/api/"+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// PushChecks records the results of external checks, adding a probe
+// for each new check.
+func (c *Client) PushChecks(results []CheckResult) error {
+	return c.postJSON("checks", results)
+}
+
+// postJSON sends v as JSON to the API path.
+func (c *Client) postJSON(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.send("POST", path, bytes.NewReader(b), "application/json", nil)
+}
+
+// Status returns a summary of the state of the dashboard.
+func (c *Client) Status() (Status, error) {
+	s := Status{}
+	return s, c.do("GET", "status", nil, &s)
+}
+
+// Probes returns the state of the probes matching the filter.
+func (c *Client) Probes(f ProbeFilter) ([]ProbeStatus, error) {
+	params := url.Values{"label": f.Labels, "name": f.Patterns}
+	if f.State != "" {
+		params.Set("state", f.State)
+	}
+	probes := []ProbeStatus{}
+	return probes, c.do("GET", "probes", params, &probes)
+}
+
+// Probe returns the state of the probe.
+func (c *Client) Probe(name string) (ProbeStatus, error) {
+	p := ProbeStatus{}
+	return p, c.do("GET", "probes/"+url.PathEscape(name), nil, &p)
+}
+
+// Results returns up to limit results of the probe, most recent first,
+// after skipping the offset most recent ones.
+func (c *Client) Results(name string, offset, limit int) ([]ProbeResult, error) {
+	params := url.Values{}
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(limit))
+	results := []ProbeResult{}
+	return results, c.do("GET", "probes/"+url.PathEscape(name)+"/results", params, &results)
+}
+
+// Ack acknowledges the alert of the probe on behalf of by, or the
+// owner of the token if it's empty.
+func (c *Client) Ack(name, by string) error {
+	params := url.Values{}
+	if by != "" {
+		params.Set("by", by)
+	}
+	return c.do("POST", "probes/"+url.PathEscape(name)+"/ack", params, nil)
+}
+
+// Run runs the probe right away, returning its result.
+func (c *Client) Run(name string) (ProbeResult, error) {
+	r := ProbeResult{}
+	return r, c.do("POST", "probes/"+url.PathEscape(name)+"/run", nil, &r)
+}
+
+// Pause stops running the probe for the duration because of the
+// reason, on behalf of the owner of the token.
+func (c *Client) Pause(name, reason string, d time.Duration) (ProbeStatus, error) {
+	params := url.Values{}
+	params.Set("reason", reason)
+	params.Set("duration", d.String())
+	p := ProbeStatus{}
+	return p, c.do("POST", "probes/"+url.PathEscape(name)+"/pause", params, &p)
+}
+
+// Resume runs the paused probe again.
+func (c *Client) Resume(name string) (ProbeStatus, error) {
+	p := ProbeStatus{}
+	return p, c.do("POST", "probes/"+url.PathEscape(name)+"/resume", nil, &p)
+}
+
+// PushResults records the results of probe runs made elsewhere, as if
+// the probes had run on the dashboard.
+func (c *Client) PushResults(results []ProbeResult) error {
+	return c.postJSON("results", results)
+}
+
+// Silences returns the active silences, or all of them.
+func (c *Client) Silences(all bool) ([]Silence, error) {
+	params := url.Values{}
+	if all {
+		params.Set("all", "true")
+	}
+	ss := []Silence{}
+	return ss, c.do("GET", "silences", params, &ss)
+}
+
+// AddSilence silences alerts of the probes matching the pattern for
+// the duration, on behalf of the owner of the token.
+func (c *Client) AddSilence(pattern, comment string, d time.Duration) (Silence, error) {
+	params := url.Values{}
+	params.Set("pattern", pattern)
+	params.Set("comment", comment)
+	params.Set("duration", d.String())
+	s := Silence{}
+	return s, c.do("POST", "silences", params, &s)
+}
+
+// ExpireSilence ends the silence now.
+func (c *Client) ExpireSilence(id int) error {
+	return c.do("DELETE", "silences/"+strconv.Itoa(id), nil, nil)
+}
+
+// Reload makes the dashboard read its probes config again.
+func (c *Client) Reload() error {
+	return c.do("POST", "reload", nil, nil)
+}
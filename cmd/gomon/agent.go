@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"hkjn.me/dashboard"
+)
+
+// agent runs the probes assigned to this location by a central
+// dashboard until interrupted, pushing their results to it, and
+// returns the exit code.
+func agent(args []string) int {
+	flags := flag.NewFlagSet("agent", flag.ContinueOnError)
+	baseURL := flags.String("url", os.Getenv("GOMON_URL"), "URL of the central dashboard, or $GOMON_URL")
+	token := flags.String("token", os.Getenv("GOMON_TOKEN"), "agent token of the agent's location, or $GOMON_TOKEN")
+	interval := flags.Duration("interval", 2*time.Minute, "how often to run the probes")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *baseURL == "" || *token == "" {
+		fmt.Fprintf(os.Stderr, "gomon: agent needs -url and -token\n")
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("gomon agent running probes for %s every %v..\n", *baseURL, *interval)
+	dashboard.RunAgent(ctx, &dashboard.Client{URL: *baseURL, Token: *token}, *interval)
+	log.Printf("gomon agent stopped\n")
+	return 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"hkjn.me/dashboard"
+)
+
+// checkConfig checks the probes config in the file given in args, or
+// the one gomon would use, printing any problems found, and returns
+// the exit code.
+func checkConfig(args []string) int {
+	var file string
+	var b []byte
+	var err error
+	if len(args) > 0 {
+		file = args[0]
+		b, err = ioutil.ReadFile(file)
+	} else if dir := os.Getenv("DASHBOARD_ASSETS_DIR"); dir != "" {
+		file = filepath.Join(dir, "probes.yaml")
+		b, err = dashboard.ReadProbesConfig(dir)
+	} else {
+		file = "probes.yaml (built in)"
+		b, err = dashboard.ReadProbesConfig("")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gomon: %v\n", err)
+		return 2
+	}
+	problems := dashboard.CheckConfig(b)
+	for _, p := range problems {
+		if p.Line == 0 {
+			fmt.Printf("%s: %s\n", file, p.Msg)
+		} else {
+			fmt.Printf("%s:%d: %s\n", file, p.Line, p.Msg)
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%s: %d problems found\n", file, len(problems))
+		return 1
+	}
+	fmt.Printf("%s: OK\n", file)
+	return 0
+}
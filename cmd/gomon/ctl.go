@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"hkjn.me/dashboard"
+)
+
+// ctlUsage describes the ctl commands.
+const ctlUsage = `usage: gomon ctl [-url url] [-token token] [-format table|json] command [args]
+
+Commands:
+  status                                    summary of the dashboard
+  probes [-state s] [-label k=v] [pattern]  list probes
+  history [-n count] [-offset n] probe      show results of a probe
+  ack [-by name] probe                      acknowledge the alert of a probe
+  run probe                                 run a probe right away
+  pause [-for d] -reason r probe            stop running a probe for a while
+  resume probe                              run a paused probe again
+  silence list [-all]                       list silences
+  silence add [-for d] [-comment c] pattern silence alerts of probes
+  silence expire id                         end a silence
+  reload                                    reload the probes config
+`
+
+// stringsFlag is a flag that may be given more than once.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// ctl runs a command against the API of a running dashboard, returning
+// the exit code.
+func ctl(args []string) int {
+	flags := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, ctlUsage) }
+	baseURL := flags.String("url", getenv("GOMON_URL", "http://localhost:8080"), "URL of the dashboard, or $GOMON_URL")
+	token := flags.String("token", os.Getenv("GOMON_TOKEN"), "API token, or $GOMON_TOKEN")
+	format := flags.String("format", "table", "output format: table or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "gomon: unknown format %q, want table or json\n", *format)
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	c := &dashboard.Client{URL: *baseURL, Token: *token}
+	out := ctlOutput{os.Stdout, *format == "json"}
+	cmd, args := flags.Arg(0), flags.Args()[1:]
+	if cmd == "silence" && len(args) > 0 {
+		cmd, args = "silence "+args[0], args[1:]
+	}
+	run, ok := ctlCommands[cmd]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gomon: unknown ctl command %q\n", cmd)
+		flags.Usage()
+		return 2
+	}
+	if err := run(c, out, args); err != nil {
+		fmt.Fprintf(os.Stderr, "gomon: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// getenv returns the environment variable, or the default if unset.
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// ctlOutput writes the output of ctl commands.
+type ctlOutput struct {
+	w      io.Writer
+	asJSON bool
+}
+
+// print writes v as JSON, or as a table by calling table otherwise.
+func (o ctlOutput) print(v interface{}, table func(w io.Writer)) error {
+	if o.asJSON {
+		e := json.NewEncoder(o.w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 8, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// ctlCommands are the ctl commands, by name.
+var ctlCommands = map[string]func(c *dashboard.Client, out ctlOutput, args []string) error{
+	"status":         ctlStatus,
+	"probes":         ctlProbes,
+	"history":        ctlHistory,
+	"ack":            ctlAck,
+	"run":            ctlRun,
+	"pause":          ctlPause,
+	"resume":         ctlResume,
+	"silence list":   ctlSilences,
+	"silence add":    ctlAddSilence,
+	"silence expire": ctlExpireSilence,
+	"reload":         ctlReload,
+}
+
+// ctlArgs parses the args of a ctl command, checking that there are
+// nargs positional ones.
+func ctlArgs(flags *flag.FlagSet, args []string, nargs int) error {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != nargs {
+		return fmt.Errorf("%s takes %d arguments, got %d", flags.Name(), nargs, flags.NArg())
+	}
+	return nil
+}
+
+// formatTime formats the time for tables.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func ctlStatus(c *dashboard.Client, out ctlOutput, args []string) error {
+	if err := ctlArgs(flag.NewFlagSet("status", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	s, err := c.Status()
+	if err != nil {
+		return err
+	}
+	return out.print(s, func(w io.Writer) {
+		fmt.Fprintf(w, "Version\t%s\n", s.Version)
+		fmt.Fprintf(w, "Started\t%s\n", formatTime(s.StartTime))
+		fmt.Fprintf(w, "Probes\t%d\n", s.Probes)
+		fmt.Fprintf(w, "Failing\t%d\n", s.Failing)
+		fmt.Fprintf(w, "Alerting\t%d\n", s.Alerting)
+		fmt.Fprintf(w, "Silenced\t%d\n", s.Silenced)
+		fmt.Fprintf(w, "Open incidents\t%d\n", s.OpenIncidents)
+		fmt.Fprintf(w, "Leader\t%v\n", s.Leader)
+	})
+}
+
+func ctlProbes(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("probes", flag.ContinueOnError)
+	state := flags.String("state", "", "only probes in the state: alerting, failing, passing, silenced or disabled")
+	labels := stringsFlag{}
+	flags.Var(&labels, "label", "only probes with the label, as key=value")
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	probes, err := c.Probes(dashboard.ProbeFilter{State: *state, Labels: labels, Patterns: flags.Args()})
+	if err != nil {
+		return err
+	}
+	return out.print(probes, func(w io.Writer) {
+		fmt.Fprintf(w, "PROBE\tKIND\tLAST\tBADNESS\tALERT\tLABELS\n")
+		for _, p := range probes {
+			last := "-"
+			if p.Last != nil {
+				last = "PASS"
+				if !p.Last.Passed {
+					last = "FAIL"
+				}
+			}
+			alert := "-"
+			switch {
+			case p.Silenced:
+				alert = "silenced"
+			case p.AckedBy != "":
+				alert = "acked by " + p.AckedBy
+			case p.Alerting:
+				alert = "ALERTING"
+			}
+			if p.Disabled {
+				last = "disabled"
+			}
+			ls := []string{}
+			for k, v := range p.Labels {
+				ls = append(ls, k+"="+v)
+			}
+			sort.Strings(ls)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", p.Name, p.Kind, last, p.Badness, alert, strings.Join(ls, ","))
+		}
+	})
+}
+
+func ctlHistory(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	n := flags.Int("n", 20, "number of results")
+	offset := flags.Int("offset", 0, "number of most recent results to skip")
+	if err := ctlArgs(flags, args, 1); err != nil {
+		return err
+	}
+	results, err := c.Results(flags.Arg(0), *offset, *n)
+	if err != nil {
+		return err
+	}
+	return out.print(results, func(w io.Writer) {
+		fmt.Fprintf(w, "TIME\tRESULT\tLATENCY\tINFO\n")
+		for _, r := range results {
+			status := "PASS"
+			if !r.Passed {
+				status = "FAIL"
+			}
+			info := strings.Join(strings.Fields(r.Info), " ")
+			fmt.Fprintf(w, "%s\t%s\t%v\t%s\n", formatTime(r.Time), status, r.Duration.Round(time.Millisecond), info)
+		}
+	})
+}
+
+func ctlAck(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("ack", flag.ContinueOnError)
+	by := flags.String("by", "", "who is handling the alert, instead of the token's owner")
+	if err := ctlArgs(flags, args, 1); err != nil {
+		return err
+	}
+	if err := c.Ack(flags.Arg(0), *by); err != nil {
+		return err
+	}
+	v := struct {
+		Probe        string `json:"probe"`
+		Acknowledged bool   `json:"acknowledged"`
+	}{flags.Arg(0), true}
+	return out.print(v, func(w io.Writer) {
+		fmt.Fprintf(w, "Alert for %s acknowledged.\n", v.Probe)
+	})
+}
+
+func ctlRun(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	if err := ctlArgs(flags, args, 1); err != nil {
+		return err
+	}
+	r, err := c.Run(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return out.print(r, func(w io.Writer) {
+		reportTable(w, []dashboard.ProbeResult{r})
+	})
+}
+
+func ctlPause(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("pause", flag.ContinueOnError)
+	d := flags.Duration("for", time.Hour, "how long to pause the probe for")
+	reason := flags.String("reason", "", "why the probe is paused")
+	if err := ctlArgs(flags, args, 1); err != nil {
+		return err
+	}
+	p, err := c.Pause(flags.Arg(0), *reason, *d)
+	if err != nil {
+		return err
+	}
+	return out.print(p, func(w io.Writer) {
+		fmt.Fprintf(w, "Probe %s paused until %s.\n", p.Name, formatTime(p.Pause.Until))
+	})
+}
+
+func ctlResume(c *dashboard.Client, out ctlOutput, args []string) error {
+	if err := ctlArgs(flag.NewFlagSet("resume", flag.ContinueOnError), args, 1); err != nil {
+		return err
+	}
+	p, err := c.Resume(args[0])
+	if err != nil {
+		return err
+	}
+	return out.print(p, func(w io.Writer) {
+		fmt.Fprintf(w, "Probe %s resumed.\n", p.Name)
+	})
+}
+
+func ctlSilences(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("silence list", flag.ContinueOnError)
+	all := flags.Bool("all", false, "include silences that have ended")
+	if err := ctlArgs(flags, args, 0); err != nil {
+		return err
+	}
+	ss, err := c.Silences(*all)
+	if err != nil {
+		return err
+	}
+	return out.print(ss, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\tPATTERN\tBY\tSTART\tEND\tCOMMENT\n")
+		for _, s := range ss {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.Pattern, s.By, formatTime(s.Start), formatTime(s.End), s.Comment)
+		}
+	})
+}
+
+func ctlAddSilence(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("silence add", flag.ContinueOnError)
+	d := flags.Duration("for", time.Hour, "how long to silence alerts for")
+	comment := flags.String("comment", "", "why alerts are silenced")
+	if err := ctlArgs(flags, args, 1); err != nil {
+		return err
+	}
+	s, err := c.AddSilence(flags.Arg(0), *comment, *d)
+	if err != nil {
+		return err
+	}
+	return out.print(s, func(w io.Writer) {
+		fmt.Fprintf(w, "Silence %d of %s added, until %s.\n", s.ID, s.Pattern, formatTime(s.End))
+	})
+}
+
+func ctlExpireSilence(c *dashboard.Client, out ctlOutput, args []string) error {
+	flags := flag.NewFlagSet("silence expire", flag.ContinueOnError)
+	if err := ctlArgs(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("bad silence ID %q", flags.Arg(0))
+	}
+	if err := c.ExpireSilence(id); err != nil {
+		return err
+	}
+	v := struct {
+		ID      int  `json:"id"`
+		Expired bool `json:"expired"`
+	}{id, true}
+	return out.print(v, func(w io.Writer) {
+		fmt.Fprintf(w, "Silence %d expired.\n", v.ID)
+	})
+}
+
+func ctlReload(c *dashboard.Client, out ctlOutput, args []string) error {
+	if err := ctlArgs(flag.NewFlagSet("reload", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	if err := c.Reload(); err != nil {
+		return err
+	}
+	v := struct {
+		Reloaded bool `json:"reloaded"`
+	}{true}
+	return out.print(v, func(w io.Writer) {
+		fmt.Fprintf(w, "Probes config reloaded.\n")
+	})
+}
@@ -1,19 +1,56 @@
 // gomon is a web tool that handles monitoring and alerting.
+//
+// Usage:
+//
+//	gomon [flags] [serve]
+//	gomon [flags] check-config [probes.yaml]
+//	gomon [flags] run-once [-format table|json|junit] [-timeout d] [pattern...]
+//	gomon [flags] ctl [-url url] [-token token] [-format table|json] command [args]
+//	gomon [flags] agent [-url url] [-token token] [-interval d]
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
 	"hkjn.me/dashboard"
 )
 
+var (
+	drainTimeout   = flag.Duration("drain_timeout", 10*time.Second, "how long to wait for requests and notifications to finish when stopping")
+	proberDisabled = flag.Bool("no_probes", false, "run passively: no probes run and no alerts are sent, but pushed results are shown")
+)
+
 func main() {
 	flag.Parse()
+	switch cmd := flag.Arg(0); cmd {
+	case "", "serve":
+		serve()
+	case "check-config":
+		os.Exit(checkConfig(flag.Args()[1:]))
+	case "run-once":
+		os.Exit(runOnce(flag.Args()[1:]))
+	case "ctl":
+		os.Exit(ctl(flag.Args()[1:]))
+	case "agent":
+		os.Exit(agent(flag.Args()[1:]))
+	default:
+		fmt.Fprintf(os.Stderr, "gomon: unknown command %q, want serve, check-config, run-once, ctl or agent\n", cmd)
+		os.Exit(2)
+	}
+}
+
+// serve runs the dashboard until interrupted.
+func serve() {
 	var conf dashboard.Config
 	err := envconfig.Process("dashboard", &conf)
 	if err != nil {
@@ -22,11 +59,38 @@ func main() {
 	if conf.BindAddr == "" {
 		conf.BindAddr = ":8080"
 	}
+	conf.ProberDisabled = conf.ProberDisabled || *proberDisabled
 	fmt.Printf("gomon initializing, listening on %s..\n", conf.BindAddr)
 
-	err = http.ListenAndServe(
-		conf.BindAddr,
-		dashboard.Start(conf),
-	)
-	log.Fatal(err.Error())
+	d, err := dashboard.New(conf)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := d.Start(ctx); err != nil {
+		log.Fatal(err.Error())
+	}
+	srv := &http.Server{
+		Addr:    conf.BindAddr,
+		Handler: d,
+	}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != http.ErrServerClosed {
+			log.Fatal(err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	fmt.Printf("gomon stopping, waiting up to %v..\n", *drainTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Printf("Failed to drain HTTP server: %v\n", err)
+	}
+	if err := d.Stop(drainCtx); err != nil {
+		log.Printf("Timed out waiting for dashboard to stop\n")
+	}
 }
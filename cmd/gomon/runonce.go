@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+
+	"hkjn.me/dashboard"
+)
+
+// runOnce runs the probes matching the patterns in args once, printing
+// a report of their results, and returns the exit code: 0 if all
+// passed, 1 if any failed and 2 if they couldn't be run.
+func runOnce(args []string) int {
+	flags := flag.NewFlagSet("run-once", flag.ContinueOnError)
+	format := flags.String("format", "table", "report format: table, json or junit")
+	timeout := flags.Duration("timeout", time.Minute, "how long to wait for probes before failing them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	report, ok := reporters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gomon: unknown format %q, want table, json or junit\n", *format)
+		return 2
+	}
+
+	var conf dashboard.Config
+	if err := envconfig.Process("dashboard", &conf); err != nil {
+		fmt.Fprintf(os.Stderr, "gomon: %v\n", err)
+		return 2
+	}
+	// Nothing is served, persisted or sent.
+	conf.Debug = true
+	conf.AlertmanagerURL = ""
+	conf.SendgridToken = ""
+	conf.LeaseFile = ""
+	conf.StateDir = ""
+	conf.StatusAddr = ""
+	d, err := dashboard.New(conf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gomon: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	results := d.RunOnce(ctx, flags.Args()...)
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "gomon: no probes match %s\n", strings.Join(flags.Args(), " "))
+		return 2
+	}
+	if err := report(os.Stdout, results); err != nil {
+		fmt.Fprintf(os.Stderr, "gomon: %v\n", err)
+		return 2
+	}
+	for _, r := range results {
+		if !r.Passed {
+			return 1
+		}
+	}
+	return 0
+}
+
+// reporters write reports of probe results, by format.
+var reporters = map[string]func(io.Writer, []dashboard.ProbeResult) error{
+	"table": reportTable,
+	"json":  reportJSON,
+	"junit": reportJUnit,
+}
+
+// reportTable writes the results as a table.
+func reportTable(w io.Writer, results []dashboard.ProbeResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "PROBE\tRESULT\tTIME\tINFO\n")
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+		}
+		info := strings.Join(strings.Fields(r.Info), " ")
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n", r.Name, status, r.Duration.Round(time.Millisecond), info)
+	}
+	return tw.Flush()
+}
+
+// reportJSON writes the results as a JSON array.
+func reportJSON(w io.Writer, results []dashboard.ProbeResult) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(results)
+}
+
+// junitSuite is a JUnit XML test suite, as understood by CI systems.
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// junitCase is a JUnit XML test case.
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure describes why a JUnit XML test case failed.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// reportJUnit writes the results as a JUnit XML test suite.
+func reportJUnit(w io.Writer, results []dashboard.ProbeResult) error {
+	s := junitSuite{Name: "gomon", Tests: len(results)}
+	for _, r := range results {
+		c := junitCase{
+			Name:      r.Name,
+			ClassName: "gomon",
+			Time:      r.Duration.Seconds(),
+		}
+		if r.Passed {
+			c.SystemOut = r.Info
+		} else {
+			s.Failures++
+			c.Failure = &junitFailure{Message: "probe failed", Text: r.Info}
+		}
+		if r.Duration.Seconds() > s.Time {
+			s.Time = r.Duration.Seconds()
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(s); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
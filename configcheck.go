@@ -0,0 +1,358 @@
+package dashboard
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigProblem is a problem found in the probes config.
+type ConfigProblem struct {
+	Line int // line of the config the problem is on, or 0 if unknown
+	Msg  string
+}
+
+func (p ConfigProblem) Error() string {
+	if p.Line == 0 {
+		return p.Msg
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Msg)
+}
+
+var (
+	// yamlLineRE matches the line number in YAML errors.
+	yamlLineRE = regexp.MustCompile(`line (\d+)`)
+	// hostnameRE matches DNS names.
+	hostnameRE = regexp.MustCompile(`^([a-zA-Z0-9_]([a-zA-Z0-9-_]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9_]([a-zA-Z0-9-_]{0,61}[a-zA-Z0-9])?\.?$`)
+)
+
+// configChecker collects problems in the probes config.
+type configChecker struct {
+	problems []ConfigProblem
+}
+
+// add records a problem on the line.
+func (c *configChecker) add(line int, format string, args ...interface{}) {
+	c.problems = append(c.problems, ConfigProblem{line, fmt.Sprintf(format, args...)})
+}
+
+// CheckConfig checks the probes config, returning any problems found,
+// ordered by line.
+//
+// Besides YAML syntax, CheckConfig looks for unknown keys, duplicate
+// probe names, malformed targets, invalid DNS records and references
+// to escalation policies or probes that don't exist.
+func CheckConfig(b []byte) []ConfigProblem {
+	c := &configChecker{}
+	root := yaml.Node{}
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		c.add(yamlErrorLine(err), "bad YAML: %v", err)
+		return c.problems
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	doc := root.Content[0]
+	c.checkKeys(doc, reflect.TypeOf(probeConfig{}), "")
+
+	probecfg := probeConfig{}
+	if err := yaml.Unmarshal(b, &probecfg); err != nil {
+		c.add(yamlErrorLine(err), "bad config: %v", err)
+		return c.sorted()
+	}
+	c.checkProbes(doc, probecfg)
+	c.checkPolicies(doc, probecfg)
+	c.checkUpstreams(doc, probecfg)
+	return c.sorted()
+}
+
+// yamlErrorLine returns the first line mentioned in the YAML error, or
+// 0 if none is.
+func yamlErrorLine(err error) int {
+	m := yamlLineRE.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	l, _ := strconv.Atoi(m[1])
+	return l
+}
+
+// sorted returns the problems, ordered by line.
+func (c *configChecker) sorted() []ConfigProblem {
+	sort.SliceStable(c.problems, func(i, j int) bool { return c.problems[i].Line < c.problems[j].Line })
+	return c.problems
+}
+
+// checkKeys reports keys in the node that don't match a field of the
+// type, recursively.
+func (c *configChecker) checkKeys(n *yaml.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			f, ok := findField(t, k.Value)
+			if !ok {
+				c.add(k.Line, "unknown key %q%s", k.Value, inPath(path))
+				continue
+			}
+			c.checkKeys(v, f.Type, path+"."+k.Value)
+		}
+	case n.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for _, item := range n.Content {
+			c.checkKeys(item, t.Elem(), path)
+		}
+	}
+}
+
+// inPath describes where in the config the path is.
+func inPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " in " + strings.TrimPrefix(path, ".")
+}
+
+// findField returns the field of the struct type that the YAML key
+// maps to, which is its lowercased name.
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.ToLower(f.Name) == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// yamlGet returns the value of the key in the mapping node, or nil.
+func yamlGet(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlItem returns the i:th item of the sequence node, or nil.
+func yamlItem(n *yaml.Node, i int) *yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode || i >= len(n.Content) {
+		return nil
+	}
+	return n.Content[i]
+}
+
+// yamlLine returns the line of the node, or 0 if it's nil.
+func yamlLine(n *yaml.Node) int {
+	if n == nil {
+		return 0
+	}
+	return n.Line
+}
+
+// checkURL reports values of the item's field that aren't http(s)
+// URLs.
+func (c *configChecker) checkURL(item *yaml.Node, field, value string) {
+	n := yamlGet(item, field)
+	if n == nil {
+		n = item
+	}
+	u, err := url.Parse(value)
+	switch {
+	case value == "":
+		c.add(yamlLine(n), "missing %s", field)
+	case err != nil:
+		c.add(yamlLine(n), "malformed %s %q: %v", field, value, err)
+	case u.Scheme != "http" && u.Scheme != "https":
+		c.add(yamlLine(n), "%s %q isn't an http or https URL", field, value)
+	case u.Host == "":
+		c.add(yamlLine(n), "%s %q has no host", field, value)
+	}
+}
+
+// checkProbes reports problems with the probes, and with references to
+// them from the status page.
+func (c *configChecker) checkProbes(doc *yaml.Node, probecfg probeConfig) {
+	names := map[string]int{} // lines of probes, by name
+	addName := func(n *yaml.Node, name string) {
+		if name == "" {
+			c.add(yamlLine(n), "probe has no name")
+			return
+		}
+		if l, ok := names[name]; ok {
+			c.add(yamlLine(n), "duplicate probe name %q, first used on line %d", name, l)
+			return
+		}
+		names[name] = yamlLine(n)
+	}
+
+	webNodes := yamlGet(doc, "webprobes")
+	for i, p := range probecfg.WebProbes {
+		n := yamlItem(webNodes, i)
+		addName(n, p.Name)
+		c.checkURL(n, "target", p.Target)
+		if p.WantStatus < 100 || p.WantStatus > 599 {
+			c.add(yamlLine(n), "web probe %q has no valid wantstatus", p.Name)
+		}
+		c.checkLocations(n, p.Name, p.Locations, p.Quorum)
+	}
+	varsNodes := yamlGet(doc, "varsprobes")
+	for i, p := range probecfg.VarsProbes {
+		n := yamlItem(varsNodes, i)
+		addName(n, p.Name)
+		c.checkURL(n, "target", p.Target)
+		if p.Key == "" {
+			c.add(yamlLine(n), "vars probe %q has no key", p.Name)
+		}
+		c.checkLocations(n, p.Name, p.Locations, p.Quorum)
+	}
+	dnsNodes := yamlGet(doc, "dnsprobes")
+	targets := map[string]int{}
+	for i, p := range probecfg.DnsProbes {
+		n := yamlItem(dnsNodes, i)
+		tn := yamlGet(n, "target")
+		if !hostnameRE.MatchString(p.Target) {
+			c.add(yamlLine(tn), "malformed DNS target %q", p.Target)
+		} else if l, ok := targets[p.Target]; ok {
+			c.add(yamlLine(tn), "duplicate DNS target %q, first used on line %d", p.Target, l)
+		} else {
+			targets[p.Target] = yamlLine(tn)
+		}
+		c.checkLocations(n, p.Target, p.Locations, p.Quorum)
+		records := yamlGet(n, "records")
+		for j, a := range p.Records.A {
+			if ip := net.ParseIP(a); ip == nil || ip.To4() == nil {
+				c.add(yamlLine(yamlItem(yamlGet(records, "a"), j)), "invalid IPv4 address %q in A records of %s", a, p.Target)
+			}
+		}
+		for j, mx := range p.Records.Mx {
+			if !hostnameRE.MatchString(mx.Host) {
+				c.add(yamlLine(yamlItem(yamlGet(records, "mx"), j)), "malformed MX host %q for %s", mx.Host, p.Target)
+			}
+		}
+		for j, ns := range p.Records.Ns {
+			if !hostnameRE.MatchString(ns) {
+				c.add(yamlLine(yamlItem(yamlGet(records, "ns"), j)), "malformed NS host %q for %s", ns, p.Target)
+			}
+		}
+		if p.Records.Cname != "" && !hostnameRE.MatchString(p.Records.Cname) {
+			c.add(yamlLine(yamlGet(records, "cname")), "malformed CNAME %q for %s", p.Records.Cname, p.Target)
+		}
+	}
+
+	// DNS probes are named by the probes package, so get their names
+	// by creating them.
+	l := newProbeLoader(probeConfig{DnsProbes: probecfg.DnsProbes})
+	for _, p := range l.getDnsProbes() {
+		names[p.Name] = 0
+	}
+
+	components := yamlGet(yamlGet(doc, "statuspage"), "components")
+	for i, comp := range probecfg.StatusPage.Components {
+		probes := yamlGet(yamlItem(components, i), "probes")
+		for j, p := range comp.Probes {
+			if _, ok := names[p]; !ok {
+				c.add(yamlLine(yamlItem(probes, j)), "status page component %q refers to unknown probe %q", comp.Name, p)
+			}
+		}
+	}
+}
+
+// checkLocations checks the locations the probe runs in, and its
+// quorum.
+func (c *configChecker) checkLocations(n *yaml.Node, name string, locations []string, quorum int) {
+	seen := map[string]bool{}
+	for j, l := range locations {
+		if seen[l] {
+			c.add(yamlLine(yamlItem(yamlGet(n, "locations"), j)), "duplicate location %q for %s", l, name)
+		}
+		seen[l] = true
+	}
+	if quorum < 0 || quorum > len(locations) {
+		c.add(yamlLine(yamlGet(n, "quorum")), "quorum %d for %s is not between 0 and its %d locations", quorum, name, len(locations))
+	}
+}
+
+// checkPolicies reports problems with escalation policies, and with
+// references to them from probes.
+func (c *configChecker) checkPolicies(doc *yaml.Node, probecfg probeConfig) {
+	policyNodes := yamlGet(doc, "escalationpolicies")
+	policies := map[string]bool{defaultPolicy: true} // the default always exists
+	seen := map[string]bool{}
+	for i, p := range probecfg.EscalationPolicies {
+		n := yamlItem(policyNodes, i)
+		if p.Name == "" {
+			c.add(yamlLine(n), "escalation policy has no name")
+		} else if seen[p.Name] {
+			c.add(yamlLine(yamlGet(n, "name")), "duplicate escalation policy %q", p.Name)
+		}
+		seen[p.Name] = true
+		policies[p.Name] = true
+		if len(p.Tiers) == 0 {
+			c.add(yamlLine(n), "escalation policy %q has no tiers", p.Name)
+		}
+		for j, t := range p.Tiers {
+			tn := yamlItem(yamlGet(n, "tiers"), j)
+			if len(t.Targets) == 0 {
+				c.add(yamlLine(tn), "tier %d of escalation policy %q has no targets", j, p.Name)
+			}
+			for _, to := range t.Targets {
+				if to == "" {
+					c.add(yamlLine(yamlGet(tn, "targets")), "tier %d of escalation policy %q has an empty target", j, p.Name)
+				}
+			}
+			if t.Timeout == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(t.Timeout); err != nil {
+				c.add(yamlLine(yamlGet(tn, "timeout")), "bad timeout %q for tier %d of escalation policy %q", t.Timeout, j, p.Name)
+			}
+		}
+	}
+
+	checkRef := func(kind string, probes *yaml.Node, i int, name, policy string) {
+		if policy != "" && !policies[policy] {
+			c.add(yamlLine(yamlGet(yamlItem(probes, i), "escalation")), "%s probe %q refers to unknown escalation policy %q", kind, name, policy)
+		}
+	}
+	for i, p := range probecfg.WebProbes {
+		checkRef("web", yamlGet(doc, "webprobes"), i, p.Name, p.Escalation)
+	}
+	for i, p := range probecfg.VarsProbes {
+		checkRef("vars", yamlGet(doc, "varsprobes"), i, p.Name, p.Escalation)
+	}
+	for i, p := range probecfg.DnsProbes {
+		checkRef("dns", yamlGet(doc, "dnsprobes"), i, p.Target, p.Escalation)
+	}
+}
+
+// checkUpstreams reports upstreams without unique names or http(s)
+// URLs.
+func (c *configChecker) checkUpstreams(doc *yaml.Node, probecfg probeConfig) {
+	nodes := yamlGet(doc, "upstreams")
+	names := map[string]bool{}
+	for i, u := range probecfg.Upstreams {
+		n := yamlItem(nodes, i)
+		if u.Name == "" {
+			c.add(yamlLine(n), "upstream has no name")
+		} else if names[u.Name] {
+			c.add(yamlLine(n), "duplicate upstream %q", u.Name)
+		}
+		names[u.Name] = true
+		c.checkURL(n, "url", u.URL)
+	}
+}
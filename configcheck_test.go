@@ -0,0 +1,67 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	cases := []struct {
+		config string
+		want   []ConfigProblem // lines, and substrings of the messages
+	}{
+		{
+			config: "webprobes:\n  - target: https://example.com\n    name: Index\n    wantstatus: 200\n",
+		},
+		{
+			config: "webprobes:\n  - target: https://example.com\n    name: Index\n    wantstatsu: 200\n",
+			want:   []ConfigProblem{{2, "no valid wantstatus"}, {4, "unknown key \"wantstatsu\""}},
+		},
+		{
+			config: "webprobes:\n  - target: example.com\n    name: Index\n    wantstatus: 200\n  - target: https://example.com\n    name: Index\n    wantstatus: 200\n",
+			want:   []ConfigProblem{{2, "isn't an http or https URL"}, {5, "duplicate probe name"}},
+		},
+		{
+			config: "dnsprobes:\n  - target: example.com\n    records:\n      a:\n        - 1.2.3.400\n",
+			want:   []ConfigProblem{{5, "invalid IPv4 address"}},
+		},
+		{
+			config: "webprobes:\n  - target: https://example.com\n    name: Index\n    wantstatus: 200\n    escalation: ops\nstatuspage:\n  components:\n    - name: Website\n      probes:\n        - Missing\n",
+			want:   []ConfigProblem{{5, "unknown escalation policy \"ops\""}, {10, "unknown probe \"Missing\""}},
+		},
+		{
+			config: "webprobes:\n  - target: https://example.com\n    name: Index\n    wantstatus: 200\n    locations: [eu, eu]\n    quorum: 3\n",
+			want:   []ConfigProblem{{5, "duplicate location \"eu\""}, {6, "quorum 3 for Index"}},
+		},
+		{
+			config: "upstreams:\n  - name: web\n    url: https://mon.example.com\n  - name: web\n    url: mon.example.com\n",
+			want:   []ConfigProblem{{4, "duplicate upstream \"web\""}, {5, "isn't an http or https URL"}},
+		},
+		{
+			config: "upstreams:\n  - name: web\n",
+			want:   []ConfigProblem{{2, "missing url"}},
+		},
+		{
+			config: "webprobes:\n  - target: [\n",
+			want:   []ConfigProblem{{2, "bad YAML"}},
+		},
+	}
+	for i, tt := range cases {
+		got := CheckConfig([]byte(tt.config))
+		if len(got) != len(tt.want) {
+			t.Fatalf("[%d] want %d problems, got %v\n", i, len(tt.want), got)
+		}
+		for j, w := range tt.want {
+			if got[j].Line != w.Line || !strings.Contains(got[j].Msg, w.Msg) {
+				t.Fatalf("[%d] want problem %d on line %d containing %q, got %v\n", i, j, w.Line, w.Msg, got[j])
+			}
+		}
+	}
+	b, err := embedded.ReadFile("probes.yaml")
+	if err != nil {
+		t.Fatalf("failed to read probes.yaml: %v\n", err)
+	}
+	if got := CheckConfig(b); len(got) > 0 {
+		t.Fatalf("want no problems in probes.yaml, got %v\n", got)
+	}
+}
@@ -2,17 +2,21 @@
 package dashboard // import "hkjn.me/dashboard"
 
 import (
-	"errors"
-	"io/ioutil"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"hkjn.me/config"
 	"hkjn.me/prober"
-	"hkjn.me/probes"
 
 	"hkjn.me/dashboard/gen"
 )
@@ -21,36 +25,71 @@ var (
 	emailTemplate = `{{define "email"}}
 The probe <a href="http://j.mp/hkjndash#{{.Name}}">{{.Name}}</a> failed enough that this alert fired, as the arbitrary metric of 'badness' is {{.Badness}}, which we can all agree is a big number.<br/>
 The description of the probe is: &ldquo;{{.Desc}}&rdquo;<br/>
-Failure details follow:<br/>
+{{with .Links}}Runbooks and docs: {{range $i, $l := .}}{{if $i}}, {{end}}<a href="{{$l.URL}}">{{$l.Name}}</a>{{end}}<br/>
+{{end}}{{with .AckURL}}If you're handling this, <a href="{{.}}">acknowledge the alert</a> to stop repeat notifications until the probe recovers.<br/>
+{{end}}Failure details follow:<br/>
 {{range $r := .Records.RecentFailures}}
   <h2>{{$r.Timestamp}} ({{$r.Ago}})</h2>
   <p>{{$r.Result.Info}}</p>
 {{end}}
 {{end}}`
-	probecfg = struct {
-		WebProbes []struct {
-			Target, Want, Name string
-			WantStatus         int
+)
+
+// probeConfig is the config in probes.yaml.
+type probeConfig struct {
+	WebProbes []struct {
+		Target, Want, Name string
+		WantStatus         int
+		Escalation         string
+		Links              []link
+		Locations          []string
+		Quorum             int
+	}
+	VarsProbes []struct {
+		Target, Name, Key, WantValue string
+		Escalation                   string
+		Links                        []link
+		Locations                    []string
+		Quorum                       int
+	}
+	DnsProbes []struct {
+		Target     string
+		Escalation string
+		Links      []link
+		Locations  []string
+		Quorum     int
+		Records    struct {
+			Cname string
+			A     []string
+			Mx    []struct {
+				Host string
+				Pref uint16
+			}
+			Ns  []string
+			Txt []string
 		}
-		VarsProbes []struct {
-			Target, Name, Key, WantValue string
+	}
+	EscalationPolicies []struct {
+		Name  string
+		Tiers []struct {
+			Targets []string
+			Timeout string
 		}
-		DnsProbes []struct {
-			Target  string
-			Records struct {
-				Cname string
-				A     []string
-				Mx    []struct {
-					Host string
-					Pref uint16
-				}
-				Ns  []string
-				Txt []string
-			}
+	}
+	Links []link
+	// Upstreams are other dashboards whose probes are shown on the
+	// index page too.
+	Upstreams []struct {
+		Name, URL string
+	}
+	StatusPage struct {
+		Title      string
+		Components []struct {
+			Name   string
+			Probes []string
 		}
-	}{}
-	loadConfigOnce = sync.Once{}
-)
+	}
+}
 
 type Config struct {
 	Debug            bool `default:"true"`
@@ -59,86 +98,457 @@ type Config struct {
 	SendgridToken    string
 	EmailSender      string
 	EmailRecipient   string
+	// HttpPrefix is the path prefix of all routes.
+	HttpPrefix string `envconfig:"HTTP_PREFIX"`
+	// ProberDisabled makes the dashboard passive: it doesn't run probes
+	// or send alerts, but serves the history of results and accepts
+	// results pushed through the API, like a standby replica.
+	ProberDisabled bool
+	// ExternalURL is the URL the dashboard is reachable on, used for
+	// links in notifications.
+	ExternalURL string
+	// AckSecret is the key for signing acknowledgement links.
+	AckSecret string
+	// StateDir is the directory to persist state like incidents in.
+	// State is only kept in memory if it's not set.
+	StateDir string
+	// StatusPath is the path of the public status page.
+	StatusPath string `default:"/status"`
+	// StatusAddr is the address to serve the public status page on,
+	// instead of alongside the dashboard, if set.
+	StatusAddr string
+	// AssetsDir is the directory to read templates and probes.yaml
+	// from, reloading templates on each request, instead of using the
+	// ones built into the binary. It's meant for development.
+	AssetsDir string `envconfig:"ASSETS_DIR"`
+	// RolesHeader is the request header that a proxy in front of the
+	// dashboard sets to the viewer's comma-separated roles, if any.
+	RolesHeader string
+	// UserHeader is the request header that a proxy in front of the
+	// dashboard sets to the viewer's name, recorded as who acts on
+	// probes and alerts from the index page.
+	UserHeader string
+	// APITokens are the tokens allowed to use the API, by the name of
+	// who uses them, as "name:token,name2:token2". The API is disabled
+	// if there are none.
+	APITokens map[string]string `envconfig:"API_TOKENS"`
+	// AgentTokens are the tokens agents use to fetch their probes and
+	// push results, by the location each is bound to, as
+	// "location:token". They give no other access to the API.
+	AgentTokens map[string]string `envconfig:"AGENT_TOKENS"`
+	// LeaseFile is the file instances of the dashboard sharing it elect
+	// a leader through, so only one sends notifications. All instances
+	// send them if it's not set.
+	LeaseFile string `envconfig:"LEASE_FILE"`
+	// InstanceID names the instance in the lease, or is the host name
+	// and process ID if not set.
+	InstanceID string `envconfig:"INSTANCE_ID"`
+	// UpstreamTokens are the API tokens for the upstreams in
+	// probes.yaml, by the upstream's name, as "name:token".
+	UpstreamTokens map[string]string `envconfig:"UPSTREAM_TOKENS"`
+	// ActionRoles are the roles, as set in RolesHeader, allowed to run,
+	// pause and resume probes and acknowledge alerts from the index
+	// page. Nobody may if there are none.
+	ActionRoles []string `envconfig:"ACTION_ROLES"`
+	// AlertmanagerURL is the base URL of an Alertmanager to post firing
+	// and resolved alerts to through its v2 API, instead of sending
+	// email, if set.
+	AlertmanagerURL string `envconfig:"ALERTMANAGER_URL"`
+	// OTLPEndpoint is the base URL of an OpenTelemetry collector to
+	// export probe runs to as spans and metrics over OTLP/HTTP, like
+	// "http://localhost:4318", if set.
+	OTLPEndpoint string `envconfig:"OTLP_ENDPOINT"`
+	// OTLPHeaders are headers to export with, like for authentication,
+	// as "name:value,name2:value2".
+	OTLPHeaders map[string]string `envconfig:"OTLP_HEADERS"`
+	// MetricsSink is where to push probe metrics to, as
+	// "statsd://host:port" for StatsD over UDP or "graphite://host:port"
+	// for Graphite's plaintext protocol over TCP, if set.
+	MetricsSink string `envconfig:"METRICS_SINK"`
+	// MetricsPrefix is what the names of metrics pushed to MetricsSink
+	// start with.
+	MetricsPrefix string `envconfig:"METRICS_PREFIX" default:"gomon"`
 }
 
-// setProbeCfg sets the config values.
-func setProbesCfg(conf Config, emailTemplate string) error {
-	if conf.Debug {
-		log.Printf("Starting in debug mode..")
-		return nil
+// Dashboard runs probes and serves their results over HTTP.
+type Dashboard struct {
+	conf Config
+	// probeSource and probeFile are where probes.yaml is read from.
+	probeSource fs.FS
+	probeFile   string
+	probecfg    probeConfig
+	// probeHash is a hash of the contents of the probes config.
+	probeHash string
+	// assets holds the templates and static files.
+	assets fs.FS
+	// reloadTemplates is set if templates are parsed again for each
+	// request, so changes on disk show up without restarting.
+	reloadTemplates bool
+	staticHashes    struct {
+		sync.Mutex
+		m map[string]string
 	}
-	// TODO(hkjn): Unify probes.Config vs dashboard.Config.
-	probes.Config.SendgridToken = conf.SendgridToken
-	if conf.SendgridToken == "" {
-		return errors.New("no DASHBOARD_SENDGRIDTOKEN specified")
+	handler       *mux.Router
+	statusHandler *mux.Router
+
+	// probeLock guards probecfg, probeHash, probes, probeInfos,
+	// probePolicies, policies, started and startTime, as probes may be
+	// added and removed, and config reloaded, while the dashboard
+	// runs.
+	probeLock     sync.RWMutex
+	probes        prober.Probes
+	probeInfos    map[string]probeInfo // how each probe was configured, by name
+	probePolicies map[string]string    // escalation policy names, by probe name
+	started       bool                 // whether probes have been started
+	startTime     time.Time            // when probes were started
+	policies      map[string]escalationPolicy
+
+	// quorumLock is held while checking whether probes running in
+	// several locations should alert.
+	quorumLock sync.Mutex
+	// checkLock is held while recording results of external checks,
+	// so they're recorded in order.
+	checkLock sync.Mutex
+
+	alerts     *alertBook
+	silences   *silenceBook
+	pauses     *pauseBook
+	incidents  *incidentLog
+	history    *resultHistory
+	events     *eventBroker
+	federation *federation
+
+	notifier  notifier
+	ackSecret []byte
+	// sending is held for reading while notifications are being sent,
+	// so stopNotifications can wait for them.
+	sending sync.RWMutex
+	// notifyStopped is set once no more notifications are sent.
+	notifyStopped bool
+	// lease elects the one instance sending notifications, if set.
+	lease      Lease
+	instanceID string
+	leader     bool // whether the lease is held, guarded by sending
+
+	// healthLock guards notifyErr and leaseErr, the errors of the
+	// latest notification and attempt to acquire the lease, if they
+	// failed.
+	healthLock sync.Mutex
+	notifyErr  error
+	leaseErr   error
+
+	// sinks export probe runs elsewhere, including otlp if set, and
+	// StatsD or Graphite if a metrics sink is configured.
+	sinks []resultSink
+	otlp  *otlpExporter
+	// webClient runs the web probes, shared across reloads.
+	webClient *http.Client
+
+	// lifetime is done once the dashboard is stopping.
+	lifetime context.Context
+	cancel   context.CancelFunc
+	stopped  chan struct{}
+}
+
+// Option configures a Dashboard.
+type Option func(*Dashboard)
+
+// ProbesConfig sets where the probes config is read from, instead of
+// probes.yaml in the assets.
+func ProbesConfig(fsys fs.FS, name string) Option {
+	return func(d *Dashboard) {
+		d.probeSource = fsys
+		d.probeFile = name
 	}
+}
 
-	if conf.EmailSender == "" {
-		return errors.New("no DASHBOARD_EMAILSENDER specified")
+// Assets sets the templates and static files to serve, instead of
+// the ones built into the binary or in Config.AssetsDir.
+func Assets(fsys fs.FS) Option {
+	return func(d *Dashboard) {
+		d.assets = fsys
 	}
-	if conf.Debug {
-		log.Printf(
-			"Sending any alert emails from %q to %q\n",
-			conf.EmailSender,
-			conf.EmailRecipient,
-		)
+}
+
+// New returns a new dashboard for the config, loading its probes.
+//
+// The probes don't run until Start is called.
+func New(conf Config, options ...Option) (*Dashboard, error) {
+	d := &Dashboard{
+		conf:       conf,
+		alerts:     newAlertBook(),
+		silences:   &silenceBook{},
+		pauses:     newPauseBook(),
+		federation: newFederation(),
+		history:    newResultHistory(),
+		events:     newEventBroker(),
+		lifetime:   context.Background(),
+		stopped:    make(chan struct{}),
 	}
-	probes.Config.Alert.Sender = conf.EmailSender
-	if conf.EmailRecipient == "" {
-		return errors.New("no DASHBOARD_EMAILRECIPIENT specified")
+	d.incidents = &incidentLog{alerts: d.alerts}
+	d.staticHashes.m = map[string]string{}
+	d.assets, d.reloadTemplates = getAssets(conf.AssetsDir)
+	d.instanceID = getInstanceID(conf.InstanceID)
+	if conf.LeaseFile != "" {
+		d.lease = FileLease{conf.LeaseFile}
 	}
-	probes.Config.Alert.Recipient = conf.EmailRecipient
-	if emailTemplate == "" {
-		return errors.New("no email template")
+	if conf.OTLPEndpoint != "" {
+		d.otlp = newOTLPExporter(conf.OTLPEndpoint, conf.OTLPHeaders, d.instanceID)
+		d.sinks = append(d.sinks, d.otlp)
+	}
+	d.webClient = newWebClient()
+	for _, o := range options {
+		o(d)
+	}
+	if d.probeSource == nil {
+		d.probeSource, d.probeFile = d.assets, "probes.yaml"
 	}
-	probes.Config.Template = emailTemplate
 
-	if conf.Debug {
-		log.Printf("These Google+ IDs are allowed access: %q\n", conf.AllowedGoogleIds)
+	var err error
+	if d.probeHash, err = loadProbesConfig(d.probeSource, d.probeFile, &d.probecfg); err != nil {
+		return nil, fmt.Errorf("couldn't load probes config: %v", err)
 	}
-	return nil
+	l := newProbeLoader(d.probecfg)
+	l.webClient = d.webClient
+	d.probes, d.probeInfos, d.probePolicies = l.loadProbes(), l.infos, l.policies
+	for _, p := range d.probes {
+		d.track(p)
+	}
+	n, err := newNotifier(conf, emailTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't set up notifications: %v", err)
+	}
+	d.notifier = n
+	if conf.MetricsSink != "" {
+		s, err := newMetricsSink(conf.MetricsSink, conf.MetricsPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't set up metrics sink: %v", err)
+		}
+		d.sinks = append(d.sinks, s)
+	}
+	if d.policies, err = loadEscalationPolicies(d.probecfg, d.probePolicies, conf.EmailRecipient); err != nil {
+		return nil, fmt.Errorf("couldn't load escalation policies: %v", err)
+	}
+	if d.ackSecret, err = getAckSecret(conf.AckSecret); err != nil {
+		return nil, fmt.Errorf("couldn't set acknowledgement secret: %v", err)
+	}
+	if conf.StateDir != "" {
+		if err := d.incidents.load(filepath.Join(conf.StateDir, "incidents.json")); err != nil {
+			return nil, fmt.Errorf("couldn't load incidents: %v", err)
+		}
+		if err := d.history.load(filepath.Join(conf.StateDir, "history.json")); err != nil {
+			return nil, fmt.Errorf("couldn't load history: %v", err)
+		}
+		if err := d.silences.load(filepath.Join(conf.StateDir, "silences.json")); err != nil {
+			return nil, fmt.Errorf("couldn't load silences: %v", err)
+		}
+	}
+	for _, tmpls := range [][]string{indexTmpls, probeTmpls, incidentsTmpls, incidentTmpls, statusTmpls, ackTmpls} {
+		if _, err := d.getTemplate(tmpls); err != nil {
+			return nil, fmt.Errorf("couldn't parse templates: %v", err)
+		}
+	}
+	d.handler = d.newRouter()
+	if conf.StatusAddr != "" {
+		d.statusHandler = d.newStatusRouter(conf.StatusPath)
+	}
+	return d, nil
 }
 
-// getIndexData returns the data for the index page.
-//
-// TODO: offer alternative auth other than defunct Google+ login
-// TODO: improve style of web page, add details like DNS records probed
-//       when clicking probe heading
-func getIndexData(w http.ResponseWriter, r *http.Request) (interface{}, error) {
-	data := struct {
-		Version string
-		Links   []struct {
-			Name, URL string
+// loadProbesConfig loads the config from the file in fsys, returning a
+// hash of its contents.
+func loadProbesConfig(fsys fs.FS, name string, probecfg *probeConfig) (hash string, err error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
 		}
-		Probes         []*prober.Probe
-		ProberDisabled bool
-	}{}
-	data.Version = gen.Version
-	data.Probes = getProbes()
-	data.ProberDisabled = *proberDisabled
-	return data, nil
+	}()
+	config.MustLoadNameFrom(name, probecfg, func(string) ([]byte, error) {
+		return b, nil
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:12], nil
 }
 
-// Start returns the HTTP routes for the dashboard.
-func Start(conf Config) *mux.Router {
-	r := func(filename string) ([]byte, error) {
-		return ioutil.ReadFile(filename)
+// ServeHTTP serves the dashboard.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.handler.ServeHTTP(w, r)
+}
+
+// Start starts the probes, and the status page server if it has its
+// own address.
+//
+// When the context is cancelled, the dashboard stops as by Stop.
+func (d *Dashboard) Start(ctx context.Context) error {
+	d.lifetime, d.cancel = context.WithCancel(ctx)
+	ctx = d.lifetime
+
+	d.probeLock.Lock()
+	d.started = true
+	d.startTime = time.Now()
+	d.probeLock.Unlock()
+	if !d.conf.ProberDisabled {
+		probes := prober.Probes{}
+		for _, p := range d.listProbes() {
+			if d.getProbeInfo(p.Name).runsHere() {
+				probes = append(probes, p)
+			}
+		}
+		log.Printf("Starting %d probes..\n", len(probes))
+		for _, p := range probes {
+			go p.Run()
+		}
 	}
-	if !conf.Debug {
-		r = func(filename string) ([]byte, error) {
-			return gen.Asset(filename)
+	if !d.conf.ProberDisabled {
+		go d.escalate(ctx)
+		go d.resumeLoop(ctx)
+		go d.quorumLoop(ctx)
+	}
+	go d.history.flushLoop(ctx)
+	exporting := sync.WaitGroup{}
+	for _, s := range d.sinks {
+		exporting.Add(1)
+		go func(s resultSink) {
+			defer exporting.Done()
+			s.flushLoop(ctx)
+		}(s)
+	}
+	go d.federate(ctx)
+	leading := make(chan struct{})
+	if d.lease != nil && !d.conf.ProberDisabled {
+		go func() {
+			d.lead(ctx)
+			close(leading)
+		}()
+	} else {
+		close(leading)
+	}
+
+	var statusSrv *http.Server
+	if d.statusHandler != nil {
+		statusSrv = &http.Server{
+			Addr:    d.conf.StatusAddr,
+			Handler: d.statusHandler,
+		}
+		go func() {
+			log.Printf("Serving status page on %s%s..\n", d.conf.StatusAddr, d.conf.StatusPath)
+			err := statusSrv.ListenAndServe()
+			if err != http.ErrServerClosed {
+				log.Printf("Status page server failed: %v\n", err)
+			}
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Printf("Stopping %d probes..\n", len(d.listProbes()))
+		if statusSrv != nil {
+			statusSrv.Close()
 		}
+		d.stopNotifications()
+		<-leading
+		d.history.flush()
+		exporting.Wait()
+		log.Printf("Dashboard stopped\n")
+		close(d.stopped)
+	}()
+	return nil
+}
+
+// Stop stops the probes, abandoning any runs in flight, and waits for
+// pending notifications to be sent and history to be saved, or for the
+// context to be done.
+//
+// prober.Probe.Run can't be stopped, so stopped probes are still
+// scheduled, but no longer do anything.
+func (d *Dashboard) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+	select {
+	case <-d.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Start starts the probes and returns the HTTP routes for the
+// dashboard, which runs until the process exits.
+//
+// Start is kept for compatibility; New gives more control.
+func Start(conf Config) *mux.Router {
+	return StartContext(context.Background(), conf).handler
+}
+
+// StartContext starts a dashboard for the config, as Start does, but
+// stops it once the context is cancelled. Stop waits for it to stop.
+func StartContext(ctx context.Context, conf Config) *Dashboard {
+	d, err := New(conf)
+	if err != nil {
+		log.Fatalf("FATAL: %v\n", err)
 	}
-	config.MustLoadNameFrom("probes.yaml", &probecfg, r)
+	d.Start(ctx)
+	return d
+}
+
+// probeView is a probe along with its alert state and config, as shown
+// on the index page.
+type probeView struct {
+	*prober.Probe
+	Alert   alertState
+	Info    probeInfo
+	Silence *Silence // active silence of the probe's alerts, if any
+	Pause   *Pause   // why the probe is paused, if it is
+	CanAct  bool     // whether the viewer may run, pause and resume it
+	CSRF    string   // token the viewer's forms acting on it must send
+	// Records are the latest results of the probe, from the history
+	// if the dashboard is passive, as the probe doesn't run then.
+	Records   prober.Records
+	Locations []locationView // latest results in each location, if any
+	// Disabled is set if the probe is disabled or paused, which is
+	// kept by the dashboard rather than the probe.
+	Disabled bool
+}
 
-	ps := getProbes()
-	log.Printf("Starting %d probes..\n", len(ps))
-	for _, p := range ps {
-		go p.Run()
+// view returns the view of the probe for the viewer of the request.
+func (d *Dashboard) view(p *prober.Probe, r *http.Request) probeView {
+	v := probeView{p, d.alerts.get(p.Name), d.getProbeInfo(p.Name), nil, nil, d.canAct(r), d.csrfToken(r), p.Records, d.getLocations(p.Name), p.Disabled}
+	if d.conf.ProberDisabled {
+		v.Records = d.history.records(p.Name, indexRecords)
+	}
+	if s, ok := d.silences.silenced(p.Name); ok {
+		v.Silence = &s
+	}
+	if pause, ok := d.pauses.get(p.Name); ok {
+		v.Pause, v.Disabled = &pause, true
 	}
+	return v
+}
 
-	if err := setProbesCfg(conf, emailTemplate); err != nil {
-		log.Fatalf("FATAL: Couldn't set probes config: %v\n", err)
+// getIndexData returns the data for the index page.
+//
+// TODO: offer alternative auth other than defunct Google+ login
+// TODO: improve style of web page
+func (d *Dashboard) getIndexData(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	data := struct {
+		Version        string
+		Links          []linkGroup
+		Probes         []probeView
+		ProberDisabled bool
+		Upstreams      []upstreamState
+	}{}
+	data.Version = gen.Version
+	data.Links = d.getLinks(d.getViewerRoles(r))
+	for _, p := range d.listProbes() {
+		data.Probes = append(data.Probes, d.view(p, r))
 	}
-	return newRouter(conf.Debug)
+	data.ProberDisabled = d.conf.ProberDisabled
+	data.Upstreams = d.listUpstreams()
+	return data, nil
 }
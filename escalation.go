@@ -0,0 +1,139 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// defaultPolicy is the escalation policy for probes that don't
+	// name one.
+	defaultPolicy = "default"
+	// escalateInterval is how often unacknowledged alerts are checked
+	// for escalation.
+	escalateInterval = time.Second * 30
+)
+
+// escalationPolicy describes who to notify about an alert as it goes
+// unacknowledged.
+type escalationPolicy struct {
+	Name  string
+	Tiers []escalationTier
+}
+
+// escalationTier is a set of notification targets, and how long to
+// wait for them to acknowledge an alert before notifying the next tier.
+type escalationTier struct {
+	Targets []string
+	Timeout time.Duration
+}
+
+// escalation records that a tier was notified about an alert.
+type escalation struct {
+	Time    time.Time
+	Tier    int
+	Targets []string
+}
+
+// loadEscalationPolicies loads the escalation policies from the probe
+// config, falling back to notifying only the recipient, if any, if
+// there is no default policy, and checks that the policies of probes
+// exist.
+func loadEscalationPolicies(probecfg probeConfig, probePolicies map[string]string, recipient string) (map[string]escalationPolicy, error) {
+	policies := map[string]escalationPolicy{}
+	for _, pc := range probecfg.EscalationPolicies {
+		if pc.Name == "" {
+			return nil, fmt.Errorf("escalation policy without name")
+		}
+		if _, ok := policies[pc.Name]; ok {
+			return nil, fmt.Errorf("duplicate escalation policy %q", pc.Name)
+		}
+		if len(pc.Tiers) == 0 {
+			return nil, fmt.Errorf("escalation policy %q has no tiers", pc.Name)
+		}
+		p := escalationPolicy{Name: pc.Name}
+		for i, tc := range pc.Tiers {
+			if len(tc.Targets) == 0 {
+				return nil, fmt.Errorf("tier %d of escalation policy %q has no targets", i, pc.Name)
+			}
+			for _, to := range tc.Targets {
+				if to == "" {
+					return nil, fmt.Errorf("tier %d of escalation policy %q has an empty target", i, pc.Name)
+				}
+			}
+			t := escalationTier{Targets: tc.Targets}
+			if tc.Timeout != "" {
+				timeout, err := time.ParseDuration(tc.Timeout)
+				if err != nil {
+					return nil, fmt.Errorf("bad timeout for tier %d of escalation policy %q: %v", i, pc.Name, err)
+				}
+				t.Timeout = timeout
+			}
+			p.Tiers = append(p.Tiers, t)
+		}
+		policies[p.Name] = p
+	}
+	if _, ok := policies[defaultPolicy]; !ok {
+		tier := escalationTier{}
+		if recipient != "" {
+			tier.Targets = []string{recipient}
+		}
+		policies[defaultPolicy] = escalationPolicy{
+			Name:  defaultPolicy,
+			Tiers: []escalationTier{tier},
+		}
+	}
+	for probe, name := range probePolicies {
+		if _, ok := policies[name]; !ok {
+			return nil, fmt.Errorf("probe %s refers to unknown escalation policy %q", probe, name)
+		}
+	}
+	return policies, nil
+}
+
+// getPolicy returns the escalation policy for the probe.
+func (d *Dashboard) getPolicy(probe string) escalationPolicy {
+	d.probeLock.RLock()
+	defer d.probeLock.RUnlock()
+	if name, ok := d.probePolicies[probe]; ok {
+		return d.policies[name]
+	}
+	return d.policies[defaultPolicy]
+}
+
+// escalate repeatedly notifies the next tier of each alert that has
+// gone unacknowledged for longer than its current tier's timeout,
+// blocking until the context is done.
+func (d *Dashboard) escalate(ctx context.Context) {
+	t := time.NewTicker(escalateInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		for _, p := range d.listProbes() {
+			a := d.alerts.get(p.Name)
+			if !a.Alerting || a.Acked() || len(a.Escalations) == 0 {
+				continue
+			}
+			if _, ok := d.silences.silenced(p.Name); ok {
+				continue
+			}
+			policy := d.getPolicy(p.Name)
+			last := a.Escalations[len(a.Escalations)-1]
+			next := last.Tier + 1
+			if next >= len(policy.Tiers) || policy.Tiers[last.Tier].Timeout == 0 {
+				continue
+			}
+			if time.Since(last.Time) < policy.Tiers[last.Tier].Timeout {
+				continue
+			}
+			log.Printf("Alert for %s unacknowledged, escalating to tier %d of policy %q\n", p.Name, next, policy.Name)
+			d.notifyTier(p.Name, a.Desc, a.Badness, a.Records, next, policy.Tiers[next].Targets)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadEscalationPolicies(t *testing.T) {
+	cases := []struct {
+		config      string
+		recipient   string
+		wantErr     string
+		wantTargets []string // targets of the default policy's first tier
+	}{
+		{"", "ops@example.com", "", []string{"ops@example.com"}},
+		{"", "", "", nil},
+		{"escalationpolicies:\n  - name: web\n    tiers:\n      - targets: []\n", "", "has no targets", nil},
+		{"escalationpolicies:\n  - name: web\n    tiers:\n      - targets: ['']\n", "", "has an empty target", nil},
+	}
+	for i, tt := range cases {
+		probecfg := probeConfig{}
+		if err := yaml.Unmarshal([]byte(tt.config), &probecfg); err != nil {
+			t.Fatalf("[%d] failed to parse config: %v\n", i, err)
+		}
+		policies, err := loadEscalationPolicies(probecfg, nil, tt.recipient)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("[%d] want error %q, got %v\n", i, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[%d] failed to load policies: %v\n", i, err)
+		}
+		if got := policies[defaultPolicy].Tiers[0].Targets; strings.Join(got, ",") != strings.Join(tt.wantTargets, ",") || len(got) != len(tt.wantTargets) {
+			t.Fatalf("[%d] want default targets %q, got %q\n", i, tt.wantTargets, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+const (
+	// eventBuffer is how many events may be queued for a subscriber
+	// before further events to it are dropped.
+	eventBuffer = 64
+	// heartbeatInterval is how often comments are sent on idle event
+	// streams, to keep proxies from closing them.
+	heartbeatInterval = time.Second * 30
+)
+
+// event is a change in probe state, pushed to the index page.
+type event struct {
+	Type     string    `json:"-"` // "result" or "alert"
+	Probe    string    `json:"probe"`
+	Time     time.Time `json:"time"`
+	Passed   bool      `json:"passed,omitempty"`
+	Info     string    `json:"info,omitempty"`
+	Alerting bool      `json:"alerting,omitempty"`
+	AckedBy  string    `json:"ackedBy,omitempty"`
+	AckedAt  time.Time `json:"ackedAt,omitempty"`
+}
+
+// eventBroker fans events out to subscribers.
+type eventBroker struct {
+	sync.Mutex
+	subs map[chan event]bool
+}
+
+// newEventBroker returns a new event broker, without subscribers.
+func newEventBroker() *eventBroker {
+	return &eventBroker{subs: map[chan event]bool{}}
+}
+
+// subscribe returns a channel receiving all events from now on.
+func (b *eventBroker) subscribe() chan event {
+	b.Lock()
+	defer b.Unlock()
+	c := make(chan event, eventBuffer)
+	b.subs[c] = true
+	return c
+}
+
+// unsubscribe stops sending events on the channel.
+func (b *eventBroker) unsubscribe(c chan event) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.subs, c)
+}
+
+// publish sends the event to all subscribers, dropping it for those
+// that are too far behind.
+func (b *eventBroker) publish(e event) {
+	b.Lock()
+	defer b.Unlock()
+	for c := range b.subs {
+		select {
+		case c <- e:
+		default:
+		}
+	}
+}
+
+// publishResult publishes the result of a probe run.
+func (d *Dashboard) publishResult(probe string, r prober.Result, t time.Time) {
+	d.events.publish(event{
+		Type:   "result",
+		Probe:  probe,
+		Time:   t,
+		Passed: r.Passed,
+		Info:   r.Info,
+	})
+}
+
+// publishAlert publishes the current alert state of the probe.
+func (d *Dashboard) publishAlert(probe string) {
+	a := d.alerts.get(probe)
+	d.events.publish(event{
+		Type:     "alert",
+		Probe:    probe,
+		Time:     time.Now(),
+		Alerting: a.Alerting,
+		AckedBy:  a.AckedBy,
+		AckedAt:  a.AckedAt,
+	})
+}
+
+// serveEvents streams events to the client as Server-Sent Events.
+func (d *Dashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported.", http.StatusInternalServerError)
+		return
+	}
+	c := d.events.subscribe()
+	defer d.events.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, "retry: 5000\n\n")
+	f.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-d.lifetime.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e := <-c:
+			b, err := json.Marshal(e)
+			if err != nil {
+				log.Printf("Failed to encode event: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
+		}
+		f.Flush()
+	}
+}
@@ -0,0 +1,126 @@
+package dashboard
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// federateInterval is how often the state of upstreams is pulled.
+	federateInterval = time.Second * 30
+	// upstreamStale is how old the state of an upstream may be before
+	// it's shown as stale.
+	upstreamStale = federateInterval * 3
+	// upstreamTimeout is how long to wait for an upstream to respond.
+	upstreamTimeout = time.Second * 10
+)
+
+// upstreamState is the state of an upstream dashboard, as last pulled.
+type upstreamState struct {
+	Name, URL string
+	Status    Status
+	Probes    []ProbeStatus
+	Updated   time.Time // when the state was last pulled, if ever
+	Err       string    // why the last pull failed, if it did
+	ErrSince  time.Time // when pulls started failing, if they are
+}
+
+// Stale returns true if the state of the upstream is out of date.
+func (u upstreamState) Stale() bool {
+	return time.Since(u.Updated) > upstreamStale
+}
+
+// ProbeURL returns the URL of the probe's page on the upstream.
+func (u upstreamState) ProbeURL(name string) string {
+	return strings.TrimSuffix(u.URL, "/") + "/probes/" + url.PathEscape(name)
+}
+
+// federation keeps the state of the upstreams, by name.
+type federation struct {
+	sync.Mutex
+	states map[string]*upstreamState
+}
+
+// newFederation returns a new federation, without upstreams.
+func newFederation() *federation {
+	return &federation{states: map[string]*upstreamState{}}
+}
+
+// listUpstreams returns copies of the state of the upstreams in the
+// config, in its order.
+func (d *Dashboard) listUpstreams() []upstreamState {
+	d.federation.Lock()
+	defer d.federation.Unlock()
+	us := []upstreamState{}
+	for _, uc := range d.getProbeConfig().Upstreams {
+		u := upstreamState{Name: uc.Name, URL: uc.URL}
+		if s, ok := d.federation.states[uc.Name]; ok && s.URL == uc.URL {
+			u = *s
+		}
+		us = append(us, u)
+	}
+	return us
+}
+
+// pull updates the state of the upstream from its API, keeping the
+// last known state if it fails.
+func (d *Dashboard) pull(name, baseURL string) {
+	c := &Client{
+		URL:        baseURL,
+		Token:      d.conf.UpstreamTokens[name],
+		HTTPClient: &http.Client{Timeout: upstreamTimeout},
+	}
+	status, err := c.Status()
+	probes := []ProbeStatus{}
+	if err == nil {
+		probes, err = c.Probes(ProbeFilter{})
+	}
+
+	d.federation.Lock()
+	defer d.federation.Unlock()
+	u, ok := d.federation.states[name]
+	if !ok || u.URL != baseURL {
+		u = &upstreamState{Name: name, URL: baseURL}
+		d.federation.states[name] = u
+	}
+	if err != nil {
+		if u.Err == "" {
+			log.Printf("Failed to pull state of upstream %s: %v\n", name, err)
+			u.ErrSince = time.Now()
+		}
+		u.Err = err.Error()
+		return
+	}
+	if u.Err != "" {
+		log.Printf("Pulled state of upstream %s again\n", name)
+	}
+	u.Status, u.Probes, u.Updated, u.Err, u.ErrSince = status, probes, time.Now(), "", time.Time{}
+}
+
+// federate repeatedly pulls the state of all upstreams in the config,
+// blocking until the context is done.
+func (d *Dashboard) federate(ctx context.Context) {
+	t := time.NewTicker(federateInterval)
+	defer t.Stop()
+	for {
+		wg := sync.WaitGroup{}
+		for _, uc := range d.getProbeConfig().Upstreams {
+			wg.Add(1)
+			go func(name, baseURL string) {
+				defer wg.Done()
+				d.pull(name, baseURL)
+			}(uc.Name, uc.URL)
+		}
+		wg.Wait()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package dashboard
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hkjn.me/prober"
+)
+
+func TestFederation(t *testing.T) {
+	up := newTestDashboard(t, Config{Debug: true, APITokens: map[string]string{"overview": "secret"}})
+	up.AddProbe(prober.NewProbe(resultProber{Info: "down"}, "WebIndex", ""))
+	up.RunOnce(up.lifetime)
+	up.alerts.raise("WebIndex")
+	srv := httptest.NewServer(up)
+	defer srv.Close()
+
+	d := newTestDashboard(t, Config{Debug: true, UpstreamTokens: map[string]string{"web": "secret", "dns": "wrong"}})
+	d.probecfg.Upstreams = []struct{ Name, URL string }{{"web", srv.URL}, {"dns", srv.URL + "/dns"}}
+	d.pull("web", srv.URL)
+	d.pull("dns", srv.URL+"/dns")
+
+	cases := []struct {
+		name    string
+		wantErr bool
+		want    []string // names of alerting probes
+	}{
+		{"web", false, []string{"WebIndex"}},
+		{"dns", true, nil},
+	}
+	us := d.listUpstreams()
+	for i, tt := range cases {
+		u := us[i]
+		if u.Name != tt.name || (u.Err != "") != tt.wantErr {
+			t.Fatalf("[%d] want upstream %s with error %v, got %+v\n", i, tt.name, tt.wantErr, u)
+		}
+		got := []string{}
+		for _, p := range u.Probes {
+			if p.Alerting {
+				got = append(got, p.Name)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Fatalf("[%d] want alerting probes %v, got %v\n", i, tt.want, got)
+		}
+	}
+	if got, want := us[0].ProbeURL("WebIndex"), srv.URL+"/probes/WebIndex"; got != want {
+		t.Fatalf("want drill-down link %s, got %s\n", want, got)
+	}
+	if !us[1].Stale() || us[0].Stale() {
+		t.Fatalf("want only unreachable upstream stale, got %v and %v\n", us[0].Stale(), us[1].Stale())
+	}
+
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if body := w.Body.String(); !strings.Contains(body, "Unreachable") || !strings.Contains(body, srv.URL+"/probes/WebIndex") {
+		t.Fatalf("want upstreams on index page, got %s\n", body)
+	}
+}
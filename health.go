@@ -0,0 +1,100 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+
+	"hkjn.me/dashboard/gen"
+)
+
+// serveHealthz reports that the process is alive.
+func (d *Dashboard) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, "ok\n")
+}
+
+// getNotReady returns the reasons the dashboard isn't ready to serve,
+// if any.
+func (d *Dashboard) getNotReady() []string {
+	reasons := []string{}
+	d.probeLock.RLock()
+	started := d.started
+	d.probeLock.RUnlock()
+	if !started {
+		reasons = append(reasons, "probes not started")
+	}
+	if d.lifetime.Err() != nil {
+		reasons = append(reasons, "stopping")
+	}
+	d.healthLock.Lock()
+	defer d.healthLock.Unlock()
+	if d.leaseErr != nil {
+		reasons = append(reasons, fmt.Sprintf("can't acquire lease: %v", d.leaseErr))
+	}
+	return reasons
+}
+
+// notified records the outcome of the latest notification, reported
+// by /version rather than readiness, so a notification outage doesn't
+// take every instance out of rotation.
+func (d *Dashboard) notified(err error) {
+	d.healthLock.Lock()
+	defer d.healthLock.Unlock()
+	d.notifyErr = err
+}
+
+// serveReadyz reports whether the config is loaded, probes are
+// scheduled and the lease can be acquired.
+func (d *Dashboard) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-cache")
+	if reasons := d.getNotReady(); len(reasons) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, reason := range reasons {
+			fmt.Fprintf(w, "not ready: %s\n", reason)
+		}
+		return
+	}
+	fmt.Fprint(w, "ready\n")
+}
+
+// serveVersion serves build info about the dashboard as JSON.
+func (d *Dashboard) serveVersion(w http.ResponseWriter, r *http.Request) {
+	d.probeLock.RLock()
+	info := struct {
+		Version    string     `json:"version"`
+		GoVersion  string     `json:"goVersion"`
+		ConfigHash string     `json:"configHash"`
+		StartTime  *time.Time `json:"startTime,omitempty"` // when probes were started, if they have been
+		Probes     int        `json:"probes"`
+		Instance   string     `json:"instance"`
+		Leader     bool       `json:"leader"` // whether this instance sends notifications
+		// NotifyError is why the latest notification failed, if it did.
+		NotifyError string `json:"notifyError,omitempty"`
+	}{
+		Version:    gen.Version,
+		GoVersion:  runtime.Version(),
+		ConfigHash: d.probeHash,
+		Probes:     len(d.probes),
+		Instance:   d.instanceID,
+	}
+	if d.started {
+		t := d.startTime
+		info.StartTime = &t
+	}
+	d.probeLock.RUnlock()
+	info.Leader = d.isLeader()
+	d.healthLock.Lock()
+	if d.notifyErr != nil {
+		info.NotifyError = d.notifyErr.Error()
+	}
+	d.healthLock.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		log.Printf("Failed to encode version: %v\n", err)
+	}
+}
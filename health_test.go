@@ -0,0 +1,46 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadyz(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true})
+	cases := []struct {
+		start     bool
+		notifyErr error
+		leaseErr  error
+		wantCode  int
+		want      string
+	}{
+		{false, nil, nil, http.StatusServiceUnavailable, "probes not started"},
+		{true, nil, nil, http.StatusOK, "ready"},
+		{true, errors.New("sendgrid is down"), nil, http.StatusOK, "ready"},
+		{true, nil, errors.New("lease.lock is locked"), http.StatusServiceUnavailable, "can't acquire lease: lease.lock is locked"},
+	}
+	for i, tt := range cases {
+		if tt.start && d.cancel == nil {
+			d.Start(context.Background())
+			defer d.Stop(context.Background())
+		}
+		d.notified(tt.notifyErr)
+		d.healthLock.Lock()
+		d.leaseErr = tt.leaseErr
+		d.healthLock.Unlock()
+		w := httptest.NewRecorder()
+		d.serveReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+		if w.Code != tt.wantCode || !strings.Contains(w.Body.String(), tt.want) {
+			t.Fatalf("[%d] want %d %q, got %d %q\n", i, tt.wantCode, tt.want, w.Code, w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		d.serveVersion(w, httptest.NewRequest("GET", "/version", nil))
+		if got := strings.Contains(w.Body.String(), `"notifyError"`); got != (tt.notifyErr != nil) {
+			t.Fatalf("[%d] want notifyError in version %v, got %s\n", i, tt.notifyErr != nil, w.Body.String())
+		}
+	}
+}
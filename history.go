@@ -0,0 +1,234 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+const (
+	// uptimeDays is how many days of daily uptime are kept per probe.
+	uptimeDays = 90
+	// maxResults is how many results are kept per probe, about a week
+	// at the default interval.
+	maxResults = 5000
+	// flushInterval is how often the history is written to disk.
+	flushInterval = time.Minute
+	dayFormat     = "2006-01-02"
+	// indexRecords is how many results are shown per probe on the
+	// index page of passive dashboards.
+	indexRecords = 20
+)
+
+// result is the outcome of a single probe run.
+type result struct {
+	Time    time.Time
+	Passed  bool
+	Info    string
+	Latency time.Duration
+}
+
+// latencyStats summarizes the latency of probe runs.
+type latencyStats struct {
+	Count                    int
+	Min, Mean, P50, P95, Max time.Duration
+}
+
+// dayStats counts the results of a probe during a day (in UTC).
+type dayStats struct {
+	Day            string
+	Passed, Failed int
+}
+
+// Total returns the number of results during the day.
+func (d dayStats) Total() int { return d.Passed + d.Failed }
+
+// Uptime returns the percentage of passing results during the day.
+func (d dayStats) Uptime() float64 {
+	if d.Total() == 0 {
+		return 100
+	}
+	return float64(d.Passed) / float64(d.Total()) * 100
+}
+
+// resultHistory keeps the history of probe results, persisting it to a
+// file if set.
+type resultHistory struct {
+	sync.Mutex
+	data struct {
+		Days    map[string][]dayStats // by probe name, oldest first
+		Results map[string][]result   // by probe name, oldest first
+	}
+	file  string
+	dirty bool
+}
+
+// newResultHistory returns a new, empty history.
+func newResultHistory() *resultHistory {
+	h := &resultHistory{}
+	h.data.Days = map[string][]dayStats{}
+	h.data.Results = map[string][]result{}
+	return h
+}
+
+// load reads the history from the file, if it exists.
+func (h *resultHistory) load(file string) error {
+	h.Lock()
+	defer h.Unlock()
+	h.file = file
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &h.data)
+}
+
+// flush writes the history to the file, if set and changed.
+func (h *resultHistory) flush() {
+	h.Lock()
+	defer h.Unlock()
+	if h.file == "" || !h.dirty {
+		return
+	}
+	b, err := json.Marshal(h.data)
+	if err == nil {
+		err = writeFileAtomic(h.file, b)
+	}
+	if err != nil {
+		log.Printf("Failed to save history to %s: %v\n", h.file, err)
+		return
+	}
+	h.dirty = false
+}
+
+// flushLoop repeatedly flushes the history, blocking until the
+// context is done.
+func (h *resultHistory) flushLoop(ctx context.Context) {
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			h.flush()
+		}
+	}
+}
+
+// record adds the result of a probe run.
+func (h *resultHistory) record(probe string, r prober.Result, t time.Time, latency time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	rs := append(h.data.Results[probe], result{t, r.Passed, r.Info, latency})
+	if len(rs) > maxResults {
+		rs = rs[len(rs)-maxResults:]
+	}
+	h.data.Results[probe] = rs
+
+	day := t.UTC().Format(dayFormat)
+	ds := h.data.Days[probe]
+	if len(ds) == 0 || ds[len(ds)-1].Day != day {
+		ds = append(ds, dayStats{Day: day})
+		if len(ds) > uptimeDays {
+			ds = ds[len(ds)-uptimeDays:]
+		}
+	}
+	if r.Passed {
+		ds[len(ds)-1].Passed++
+	} else {
+		ds[len(ds)-1].Failed++
+	}
+	h.data.Days[probe] = ds
+	h.dirty = true
+}
+
+// results returns up to n results of the probe, most recent first,
+// after skipping the offset most recent ones, as well as the total
+// number of results.
+func (h *resultHistory) results(probe string, offset, n int) ([]result, int) {
+	h.Lock()
+	defer h.Unlock()
+	all := h.data.Results[probe]
+	rs := []result{}
+	if offset < 0 {
+		offset = 0
+	}
+	for i := len(all) - 1 - offset; i >= 0 && len(rs) < n; i-- {
+		rs = append(rs, all[i])
+	}
+	return rs, len(all)
+}
+
+// records returns the n latest results of the probe as probe records,
+// oldest first.
+func (h *resultHistory) records(probe string, n int) prober.Records {
+	rs, _ := h.results(probe, 0, n)
+	records := prober.Records{}
+	for i := len(rs) - 1; i >= 0; i-- {
+		records = append(records, &prober.Record{
+			Timestamp: rs[i].Time,
+			Result:    prober.Result{Passed: rs[i].Passed, Info: rs[i].Info},
+		})
+	}
+	return records
+}
+
+// latency returns latency stats over the results of the probe.
+func (h *resultHistory) latency(probe string) latencyStats {
+	h.Lock()
+	ls := make([]time.Duration, len(h.data.Results[probe]))
+	for i, r := range h.data.Results[probe] {
+		ls[i] = r.Latency
+	}
+	h.Unlock()
+	if len(ls) == 0 {
+		return latencyStats{}
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i] < ls[j] })
+	var sum time.Duration
+	for _, l := range ls {
+		sum += l
+	}
+	return latencyStats{
+		Count: len(ls),
+		Min:   ls[0],
+		Mean:  sum / time.Duration(len(ls)),
+		P50:   ls[len(ls)*50/100],
+		P95:   ls[len(ls)*95/100],
+		Max:   ls[len(ls)-1],
+	}
+}
+
+// uptime returns the daily stats of the probes combined for each of the
+// last n days, oldest first.
+func (h *resultHistory) uptime(probes []string, n int) []dayStats {
+	h.Lock()
+	defer h.Unlock()
+	byDay := map[string]dayStats{}
+	for _, p := range probes {
+		for _, d := range h.data.Days[p] {
+			s := byDay[d.Day]
+			s.Passed += d.Passed
+			s.Failed += d.Failed
+			byDay[d.Day] = s
+		}
+	}
+	ds := make([]dayStats, n)
+	today := time.Now().UTC()
+	for i := range ds {
+		day := today.AddDate(0, 0, i-n+1).Format(dayFormat)
+		ds[i] = byDay[day]
+		ds[i].Day = day
+	}
+	return ds
+}
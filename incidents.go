@@ -0,0 +1,274 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// incident is a period where one or more probes were alerting.
+type incident struct {
+	ID         int
+	Start, End time.Time
+	Probes     []string // probes affected by the incident
+	Events     []incidentEvent
+}
+
+// Open returns true if the incident is ongoing.
+func (i incident) Open() bool { return i.End.IsZero() }
+
+// Duration returns how long the incident lasted, or has lasted so far.
+func (i incident) Duration() time.Duration {
+	if i.Open() {
+		return time.Since(i.Start).Round(time.Second)
+	}
+	return i.End.Sub(i.Start).Round(time.Second)
+}
+
+// incidentEvent is an entry in the timeline of an incident.
+type incidentEvent struct {
+	Time  time.Time
+	Kind  string // "alert", "notified", "escalated", "ack", "recovered", "removed", "note", "closed"
+	Probe string // the probe the event is about, if any
+	By    string // who caused the event, if anyone
+	Text  string
+}
+
+// incidentLog holds all incidents, persisting them to a file if set.
+type incidentLog struct {
+	sync.Mutex
+	all    []*incident
+	file   string
+	alerts *alertBook // alert state of the probes
+}
+
+// load reads the incidents from the file, if it exists.
+func (l *incidentLog) load(file string) error {
+	l.Lock()
+	defer l.Unlock()
+	l.file = file
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &l.all); err != nil {
+		return err
+	}
+	// Alert state isn't persisted, so an incident left open by an
+	// earlier run can't be tracked to its end.
+	if i := l.open(); i != nil {
+		now := time.Now()
+		i.End = now
+		i.Events = append(i.Events, incidentEvent{Time: now, Kind: "closed", Text: "Dashboard restarted during incident"})
+	}
+	return nil
+}
+
+// save writes the incidents to the file, if set. The lock must be held.
+func (l *incidentLog) save() {
+	if l.file == "" {
+		return
+	}
+	b, err := json.Marshal(l.all)
+	if err == nil {
+		err = writeFileAtomic(l.file, b)
+	}
+	if err != nil {
+		log.Printf("Failed to save incidents to %s: %v\n", l.file, err)
+	}
+}
+
+// open returns the ongoing incident, or nil. The lock must be held.
+func (l *incidentLog) open() *incident {
+	if len(l.all) == 0 || !l.all[len(l.all)-1].Open() {
+		return nil
+	}
+	return l.all[len(l.all)-1]
+}
+
+// probeAlerting records that the probe started alerting, opening an
+// incident unless one is ongoing.
+func (l *incidentLog) probeAlerting(probe string) {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	i := l.open()
+	if i == nil {
+		i = &incident{ID: len(l.all) + 1, Start: now}
+		l.all = append(l.all, i)
+		log.Printf("Opened incident %d for %s\n", i.ID, probe)
+	}
+	found := false
+	for _, p := range i.Probes {
+		found = found || p == probe
+	}
+	if !found {
+		i.Probes = append(i.Probes, probe)
+	}
+	i.Events = append(i.Events, incidentEvent{Time: now, Kind: "alert", Probe: probe, Text: "Probe started alerting"})
+	l.save()
+}
+
+// probeRecovered records that the probe stopped alerting, closing the
+// ongoing incident if none of its probes are alerting any longer.
+func (l *incidentLog) probeRecovered(probe string) {
+	l.probeStopped(incidentEvent{Kind: "recovered", Probe: probe, Text: "Probe recovered"})
+}
+
+// probeRemoved records that the probe was removed while alerting,
+// closing the ongoing incident if none of its probes are alerting any
+// longer.
+func (l *incidentLog) probeRemoved(probe string) {
+	l.probeStopped(incidentEvent{Kind: "removed", Probe: probe, Text: "Probe removed"})
+}
+
+// probeStopped adds the event about a probe that stopped alerting,
+// closing the ongoing incident if none of its probes are alerting any
+// longer.
+func (l *incidentLog) probeStopped(e incidentEvent) {
+	l.Lock()
+	defer l.Unlock()
+	i := l.open()
+	if i == nil {
+		return
+	}
+	now := time.Now()
+	e.Time = now
+	i.Events = append(i.Events, e)
+	for _, p := range i.Probes {
+		if l.alerts.get(p).Alerting {
+			l.save()
+			return
+		}
+	}
+	i.End = now
+	i.Events = append(i.Events, incidentEvent{Time: now, Kind: "closed", Text: "All probes recovered"})
+	log.Printf("Closed incident %d after %v\n", i.ID, i.Duration())
+	l.save()
+}
+
+// record adds the event to the ongoing incident, if any.
+func (l *incidentLog) record(e incidentEvent) {
+	l.Lock()
+	defer l.Unlock()
+	i := l.open()
+	if i == nil {
+		return
+	}
+	e.Time = time.Now()
+	i.Events = append(i.Events, e)
+	l.save()
+}
+
+// addNote adds a free-form note to the incident.
+func (l *incidentLog) addNote(id int, by, text string) error {
+	if text == "" {
+		return errors.New("empty note")
+	}
+	l.Lock()
+	defer l.Unlock()
+	if id < 1 || id > len(l.all) {
+		return errNotFound
+	}
+	i := l.all[id-1]
+	i.Events = append(i.Events, incidentEvent{Time: time.Now(), Kind: "note", By: by, Text: text})
+	l.save()
+	return nil
+}
+
+// get returns a copy of the incident with the ID.
+func (l *incidentLog) get(id int) (incident, bool) {
+	l.Lock()
+	defer l.Unlock()
+	if id < 1 || id > len(l.all) {
+		return incident{}, false
+	}
+	return *l.all[id-1], true
+}
+
+// list returns copies of all incidents, most recent first.
+func (l *incidentLog) list() []incident {
+	l.Lock()
+	defer l.Unlock()
+	is := make([]incident, len(l.all))
+	for i, inc := range l.all {
+		is[i] = *inc
+	}
+	sort.Slice(is, func(i, j int) bool { return is[i].ID > is[j].ID })
+	return is
+}
+
+// writeFileAtomic writes the data to the file by way of a temporary
+// file, so readers never see partial contents.
+func writeFileAtomic(file string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// getIncidentsData returns the data for the incidents page.
+func (d *Dashboard) getIncidentsData(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return struct {
+		Incidents []incident
+	}{d.incidents.list()}, nil
+}
+
+// getIncidentData returns the data for the page of a single incident.
+func (d *Dashboard) getIncidentData(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errNotFound
+	}
+	i, ok := d.incidents.get(id)
+	if !ok {
+		return nil, errNotFound
+	}
+	return struct {
+		Incident incident
+		CanAct   bool   // whether the viewer may add notes
+		CSRF     string // token the notes form must send
+	}{i, d.canAct(r), d.csrfToken(r)}, nil
+}
+
+// addIncidentNote adds a note to an incident from the form on its page,
+// by the viewer.
+func (d *Dashboard) addIncidentNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = d.incidents.addNote(id, d.getViewer(r), r.FormValue("text"))
+	if err == errNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s/incidents/%d", d.conf.HttpPrefix, id), http.StatusSeeOther)
+}
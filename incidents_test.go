@@ -0,0 +1,72 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIncidentLifecycle(t *testing.T) {
+	alerts := newAlertBook()
+	incidents := &incidentLog{alerts: alerts}
+
+	alerts.raise("WebIndex")
+	incidents.probeAlerting("WebIndex")
+	alerts.raise("NakedIndex")
+	incidents.probeAlerting("NakedIndex")
+	if got := incidents.list(); len(got) != 1 || len(got[0].Probes) != 2 {
+		t.Fatalf("want one incident affecting two probes, got %+v\n", got)
+	}
+
+	alerts.clear("WebIndex")
+	incidents.probeRecovered("WebIndex")
+	if i, _ := incidents.get(1); !i.Open() {
+		t.Fatalf("want incident open while NakedIndex is alerting, got %+v\n", i)
+	}
+
+	alerts.clear("NakedIndex")
+	incidents.probeRecovered("NakedIndex")
+	if i, _ := incidents.get(1); i.Open() {
+		t.Fatalf("want incident closed after all probes recovered, got %+v\n", i)
+	}
+
+	alerts.raise("WebIndex")
+	incidents.probeAlerting("WebIndex")
+	if got := incidents.list(); len(got) != 2 || got[0].ID != 2 || !got[0].Open() {
+		t.Fatalf("want new open incident 2, got %+v\n", got)
+	}
+}
+
+func TestIncidentNotes(t *testing.T) {
+	cases := []struct {
+		roles    string
+		wantCode int
+		wantBy   string // author of the note, if added
+	}{
+		{"", http.StatusForbidden, ""},
+		{"oncall", http.StatusSeeOther, "alice"},
+	}
+	for i, tt := range cases {
+		d := newTestDashboard(t, Config{Debug: true, RolesHeader: "X-Roles", UserHeader: "X-User", ActionRoles: []string{"oncall"}})
+		d.alerts.raise("WebIndex")
+		d.incidents.probeAlerting("WebIndex")
+		req, err := http.NewRequest("POST", "/incidents/1/notes", strings.NewReader("text=rolled+back&by=mallory&csrf="+csrfFor(d, "alice")))
+		if err != nil {
+			t.Fatalf("[%d] failed to create request: %v\n", i, err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Roles", tt.roles)
+		req.Header.Set("X-User", "alice")
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Fatalf("[%d] want HTTP response %d, got %d: %s\n", i, tt.wantCode, w.Code, w.Body.String())
+		}
+		inc, _ := d.incidents.get(1)
+		last := inc.Events[len(inc.Events)-1]
+		if got := last.Kind == "note"; got != (tt.wantBy != "") || (got && last.By != tt.wantBy) {
+			t.Fatalf("[%d] want note by %q, got %+v\n", i, tt.wantBy, last)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	// leaseTTL is how long a lease lasts unless renewed.
+	leaseTTL = time.Second * 30
+	// leaseRenewInterval is how often the lease is renewed, or tried
+	// to be taken by followers.
+	leaseRenewInterval = leaseTTL / 3
+	// fileLockPoll is how often to try taking the lock of a lease
+	// file held by another dashboard.
+	fileLockPoll = time.Millisecond * 10
+)
+
+// Lease elects a single leader among dashboards sharing it. Only the
+// leader sends notifications.
+type Lease interface {
+	// Acquire takes the lease for the holder for the duration, or
+	// renews it if the holder already has it, returning whether the
+	// holder has it.
+	Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+	// Release gives up the lease if the holder has it, so another
+	// holder can take it right away.
+	Release(ctx context.Context, holder string) error
+}
+
+// SharedState is implemented by leases that also keep state shared by
+// the dashboards holding them, so acknowledgements, silences and
+// escalations carry over to whichever dashboard leads.
+type SharedState interface {
+	// UpdateState calls update with the shared state, which is empty
+	// if there's none yet, and stores what it returns, with no other
+	// dashboard updating it meanwhile.
+	UpdateState(ctx context.Context, update func(state []byte) ([]byte, error)) error
+}
+
+// LeaderLease makes the dashboard only send notifications while it
+// holds the lease, instead of always.
+func LeaderLease(l Lease) Option {
+	return func(d *Dashboard) {
+		d.lease = l
+	}
+}
+
+// FileLease is a lease kept in a file, shared by dashboards on the
+// same host or a shared volume, which keeps their shared state in
+// another file next to it. The clocks of the dashboards should be in
+// sync.
+type FileLease struct {
+	Path string
+}
+
+// fileLeaseState is what's kept in a lease file.
+type fileLeaseState struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// lock takes the lock file next to the lease file, waiting until the
+// context is done, returning a func to release it.
+func (l FileLease) lock(ctx context.Context) (func(), error) {
+	f, err := os.OpenFile(l.Path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(ctx, f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("can't lock %s: %v", f.Name(), err)
+	}
+	return func() { f.Close() }, nil
+}
+
+// read returns the state of the lease, which is empty if there's no
+// lease file.
+func (l FileLease) read() (fileLeaseState, error) {
+	s := fileLeaseState{}
+	b, err := ioutil.ReadFile(l.Path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal(b, &s)
+}
+
+// Acquire takes or renews the lease, if it isn't held by another
+// holder that has renewed it within the duration.
+func (l FileLease) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	unlock, err := l.lock(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	s, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if s.Holder != "" && s.Holder != holder && now.Before(s.Expires) {
+		return false, nil
+	}
+	b, err := json.Marshal(fileLeaseState{holder, now.Add(ttl)})
+	if err != nil {
+		return false, err
+	}
+	if err := writeFileAtomic(l.Path, b); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Release removes the lease file, if the holder has the lease.
+func (l FileLease) Release(ctx context.Context, holder string) error {
+	unlock, err := l.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s, err := l.read()
+	if err != nil {
+		return err
+	}
+	if s.Holder != holder {
+		return errors.New("lease is held by " + s.Holder)
+	}
+	return os.Remove(l.Path)
+}
+
+// UpdateState updates the shared state, kept in the lease file's path
+// with a ".state" suffix.
+func (l FileLease) UpdateState(ctx context.Context, update func(state []byte) ([]byte, error)) error {
+	unlock, err := l.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	name := l.Path + ".state"
+	b, err := ioutil.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	b, err = update(b)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(name, b)
+}
+
+// getInstanceID returns the name the dashboard holds its lease by.
+func getInstanceID(id string) string {
+	if id != "" {
+		return id
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// isLeader returns true if the dashboard may send notifications,
+// which it may if it holds the lease or there is none.
+func (d *Dashboard) isLeader() bool {
+	if d.lease == nil {
+		return true
+	}
+	d.sending.RLock()
+	defer d.sending.RUnlock()
+	return d.leader
+}
+
+// setLeader records whether the dashboard holds the lease, waiting for
+// notifications being sent to finish.
+func (d *Dashboard) setLeader(leader bool) {
+	d.sending.Lock()
+	defer d.sending.Unlock()
+	if leader != d.leader {
+		if leader {
+			log.Printf("%s is now the leader, sending notifications\n", d.instanceID)
+		} else {
+			log.Printf("%s is no longer the leader, not sending notifications\n", d.instanceID)
+		}
+	}
+	d.leader = leader
+}
+
+// lead repeatedly takes or renews the lease, blocking until the
+// context is done, when it gives up the lease. The dashboard steps
+// down if it can't tell whether it holds the lease.
+func (d *Dashboard) lead(ctx context.Context) {
+	t := time.NewTicker(leaseRenewInterval)
+	defer t.Stop()
+	for {
+		ok, err := d.lease.Acquire(ctx, d.instanceID, leaseTTL)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to acquire lease: %v\n", err)
+		}
+		if err == nil {
+			if err := d.shareState(nil); err != nil {
+				log.Printf("Failed to sync shared state: %v\n", err)
+			}
+		}
+		d.healthLock.Lock()
+		d.leaseErr = err
+		d.healthLock.Unlock()
+		d.setLeader(ok && err == nil)
+		select {
+		case <-ctx.Done():
+			d.releaseLease()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// releaseLease steps down and gives up the lease, if held, so another
+// dashboard can take over right away.
+func (d *Dashboard) releaseLease() {
+	if !d.isLeader() {
+		return
+	}
+	d.setLeader(false)
+	ctx, cancel := context.WithTimeout(context.Background(), leaseRenewInterval)
+	defer cancel()
+	if err := d.lease.Release(ctx, d.instanceID); err != nil {
+		log.Printf("Failed to release lease: %v\n", err)
+	}
+}
+
+// sharedState is the state dashboards share through their lease.
+type sharedState struct {
+	Alerts   map[string]sharedAlert `json:"alerts"`
+	Silences []*Silence             `json:"silences"`
+}
+
+// sharedAlert is the state of an alert shared by dashboards.
+type sharedAlert struct {
+	AckedBy     string       `json:"ackedBy,omitempty"`
+	AckedAt     time.Time    `json:"ackedAt"`
+	Escalations []escalation `json:"escalations"`
+}
+
+// shareState calls change, if any, and syncs alerts and silences with
+// the dashboards sharing the lease, if it keeps shared state. Silences
+// are first replaced by the shared ones, so change can add to them.
+func (d *Dashboard) shareState(change func() error) error {
+	ss, ok := d.lease.(SharedState)
+	if !ok {
+		if change != nil {
+			return change()
+		}
+		return nil
+	}
+	leader := d.isLeader()
+	acked := []string{}
+	ctx, cancel := context.WithTimeout(context.Background(), leaseRenewInterval)
+	defer cancel()
+	err := ss.UpdateState(ctx, func(b []byte) ([]byte, error) {
+		s := sharedState{}
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, &s); err != nil {
+				return nil, err
+			}
+		}
+		if s.Alerts == nil {
+			s.Alerts = map[string]sharedAlert{}
+		}
+		if s.Silences != nil {
+			d.silences.replace(s.Silences)
+		}
+		if change != nil {
+			if err := change(); err != nil {
+				return nil, err
+			}
+		}
+		acked = d.alerts.sync(s.Alerts, leader)
+		s.Silences = d.silences.copies()
+		return json.Marshal(s)
+	})
+	for _, name := range acked {
+		d.incidents.record(incidentEvent{Kind: "ack", Probe: name, By: d.alerts.get(name).AckedBy, Text: "Alert acknowledged"})
+		d.publishAlert(name)
+	}
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// lockFile fails, as there's no flock on this platform.
+func lockFile(ctx context.Context, f *os.File) error {
+	return fmt.Errorf("file leases aren't supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,122 @@
+package dashboard
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingNotifier counts the notifications sent.
+type countingNotifier struct{ sent *int }
+
+func (n countingNotifier) Notify(to string, _ notification) error {
+	*n.sent++
+	return nil
+}
+
+func TestFileLease(t *testing.T) {
+	ctx := context.Background()
+	l := FileLease{filepath.Join(t.TempDir(), "lease")}
+	cases := []struct {
+		wait    time.Duration // how long to wait first
+		holder  string
+		release bool
+		ttl     time.Duration
+		want    bool
+	}{
+		{0, "a", false, time.Minute, true},
+		{0, "b", false, time.Minute, false},
+		{0, "a", false, time.Millisecond, true},
+		{10 * time.Millisecond, "b", false, time.Minute, true},
+		{0, "b", true, 0, true},
+		{0, "a", false, time.Minute, true},
+	}
+	for i, tt := range cases {
+		time.Sleep(tt.wait)
+		if tt.release {
+			if err := l.Release(ctx, tt.holder); err != nil {
+				t.Fatalf("[%d] failed to release lease: %v\n", i, err)
+			}
+			continue
+		}
+		got, err := l.Acquire(ctx, tt.holder, tt.ttl)
+		if err != nil || got != tt.want {
+			t.Fatalf("[%d] want %v acquiring lease for %s, got %v, %v\n", i, tt.want, tt.holder, got, err)
+		}
+	}
+
+	l = FileLease{filepath.Join(t.TempDir(), "lease")}
+	won := make(chan bool)
+	for _, holder := range []string{"a", "b", "c", "d"} {
+		go func(holder string) {
+			ok, err := l.Acquire(ctx, holder, time.Minute)
+			won <- ok && err == nil
+		}(holder)
+	}
+	winners := 0
+	for i := 0; i < 4; i++ {
+		if <-won {
+			winners++
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("want 1 of 4 dashboards acquiring the lease at once, got %d\n", winners)
+	}
+
+	sent := 0
+	d := newTestDashboard(t, Config{Debug: true, InstanceID: "b"})
+	LeaderLease(l)(d)
+	d.notifier = countingNotifier{&sent}
+	d.setLeader(false)
+	d.notifyTargets([]string{"ops@example.com"}, "WebIndex", "", 100, nil)
+	if sent != 0 {
+		t.Fatalf("want no notifications from follower, got %d\n", sent)
+	}
+	d.setLeader(true)
+	d.notifyTargets([]string{"ops@example.com"}, "WebIndex", "", 100, nil)
+	if sent != 1 {
+		t.Fatalf("want 1 notification from leader, got %d\n", sent)
+	}
+}
+
+func TestSharedState(t *testing.T) {
+	l := FileLease{filepath.Join(t.TempDir(), "lease")}
+	sent := 0
+	leader := newTestDashboard(t, Config{Debug: true, InstanceID: "a"})
+	follower := newTestDashboard(t, Config{Debug: true, InstanceID: "b"})
+	for _, d := range []*Dashboard{leader, follower} {
+		LeaderLease(l)(d)
+		d.notifier = countingNotifier{&sent}
+		d.alerts.raise("WebIndex")
+		d.incidents.probeAlerting("WebIndex")
+	}
+	leader.setLeader(true)
+
+	follower.notifyTier("WebIndex", "", 100, nil, 0, []string{"ops@example.com"})
+	if got := follower.alerts.get("WebIndex").Escalations; len(got) != 0 || sent != 0 {
+		t.Fatalf("want no escalations or notifications by follower, got %+v and %d sent\n", got, sent)
+	}
+	leader.notifyTier("WebIndex", "", 100, nil, 0, []string{"ops@example.com"})
+	if err := follower.acknowledge("WebIndex", "alice"); err != nil {
+		t.Fatalf("failed to acknowledge alert: %v\n", err)
+	}
+	if _, err := follower.addSilence("Dns*", "alice", "migrating DNS", time.Hour); err != nil {
+		t.Fatalf("failed to add silence: %v\n", err)
+	}
+	if err := leader.shareState(nil); err != nil {
+		t.Fatalf("failed to sync shared state: %v\n", err)
+	}
+	if got := leader.alerts.get("WebIndex"); got.AckedBy != "alice" {
+		t.Fatalf("want alert acked on follower acked on leader, got %+v\n", got)
+	}
+	if _, ok := leader.silences.silenced("DnsMX"); !ok {
+		t.Fatalf("want silence added on follower active on leader, got %+v\n", leader.silences.list(false))
+	}
+	if err := follower.shareState(nil); err != nil {
+		t.Fatalf("failed to sync shared state: %v\n", err)
+	}
+	if got := follower.alerts.get("WebIndex").Escalations; len(got) != 1 {
+		t.Fatalf("want escalation by leader on follower, got %+v\n", got)
+	}
+}
@@ -0,0 +1,82 @@
+package dashboard
+
+import (
+	"net/http"
+	"strings"
+)
+
+// link is a link shown on the dashboard.
+type link struct {
+	Name, URL string
+	Group     string   // heading to show the link under, if any
+	Icon      string   // URL of an icon to show with the link, if any
+	Roles     []string // roles allowed to see the link, or empty for everyone
+}
+
+// linkGroup is a group of links shown under the same heading.
+type linkGroup struct {
+	Name  string
+	Links []link
+}
+
+// visibleTo returns true if a viewer with the roles may see the link.
+func (l link) visibleTo(roles []string) bool {
+	if len(l.Roles) == 0 {
+		return true
+	}
+	for _, want := range l.Roles {
+		for _, r := range roles {
+			if r == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getLinks returns the configured links visible to a viewer with the
+// roles, grouped in the order the groups first appear.
+func (d *Dashboard) getLinks(roles []string) []linkGroup {
+	groups := []linkGroup{}
+	index := map[string]int{}
+	for _, l := range d.getProbeConfig().Links {
+		if !l.visibleTo(roles) {
+			continue
+		}
+		i, ok := index[l.Group]
+		if !ok {
+			i = len(groups)
+			index[l.Group] = i
+			groups = append(groups, linkGroup{Name: l.Group})
+		}
+		groups[i].Links = append(groups[i].Links, l)
+	}
+	return groups
+}
+
+// getViewer returns the name of the viewer of the request, as set by
+// a proxy in front of the dashboard in the configured header, or "the
+// index page" if there's none.
+func (d *Dashboard) getViewer(r *http.Request) string {
+	if d.conf.UserHeader != "" {
+		if who := strings.TrimSpace(r.Header.Get(d.conf.UserHeader)); who != "" {
+			return who
+		}
+	}
+	return "the index page"
+}
+
+// getViewerRoles returns the roles of the viewer of the request, as set
+// by a proxy in front of the dashboard in the configured header.
+func (d *Dashboard) getViewerRoles(r *http.Request) []string {
+	if d.conf.RolesHeader == "" {
+		return nil
+	}
+	roles := []string{}
+	for _, role := range strings.Split(r.Header.Get(d.conf.RolesHeader), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
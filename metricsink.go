@@ -0,0 +1,177 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// metricsInterval is how often metrics are pushed to StatsD or
+	// Graphite.
+	metricsInterval = time.Second * 10
+	// metricsTimeout is how long to wait for StatsD or Graphite to
+	// accept metrics.
+	metricsTimeout = time.Second * 5
+	// maxMetricLines is how many lines are kept between pushes,
+	// dropping the oldest ones beyond that.
+	maxMetricLines = 10000
+	// maxStatsdPacket is how large StatsD packets may be, to fit in a
+	// single Ethernet frame.
+	maxStatsdPacket = 1432
+)
+
+// metricNameChars matches characters not allowed in metric names.
+var metricNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// metricsSink pushes probe latency, pass and fail counts and badness
+// to StatsD over UDP, or Graphite's plaintext protocol over TCP.
+type metricsSink struct {
+	graphite bool // whether to push to Graphite instead of StatsD
+	addr     string
+	prefix   string
+
+	sync.Mutex
+	lines  []string
+	counts map[string][2]int64 // runs passed and failed by probe, for Graphite
+}
+
+// newMetricsSink returns a sink pushing to the URL, which is
+// "statsd://host:port" or "graphite://host:port", with metric names
+// starting with the prefix.
+func newMetricsSink(rawURL, prefix string) (*metricsSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "statsd" && u.Scheme != "graphite" {
+		return nil, fmt.Errorf("metrics sink %q isn't statsd:// or graphite://", rawURL)
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("metrics sink %q has no port", rawURL)
+	}
+	return &metricsSink{
+		graphite: u.Scheme == "graphite",
+		addr:     u.Host,
+		prefix:   strings.TrimSuffix(prefix, "."),
+		counts:   map[string][2]int64{},
+	}, nil
+}
+
+// metricName returns the name of the probe's metric.
+func (s *metricsSink) metricName(probe, metric string) string {
+	name := metricNameChars.ReplaceAllString(probe, "_") + "." + metric
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "." + name
+}
+
+// record adds the metrics of the run to the next push.
+func (s *metricsSink) record(r probeRun) {
+	s.Lock()
+	defer s.Unlock()
+	ms := r.Duration.Milliseconds()
+	lines := []string{}
+	if s.graphite {
+		c := s.counts[r.Name]
+		if r.Result.Passed {
+			c[0]++
+		} else {
+			c[1]++
+		}
+		s.counts[r.Name] = c
+		ts := r.Start.Add(r.Duration).Unix()
+		lines = append(lines,
+			fmt.Sprintf("%s %d %d", s.metricName(r.Name, "latency_ms"), ms, ts),
+			fmt.Sprintf("%s %d %d", s.metricName(r.Name, "passed"), c[0], ts),
+			fmt.Sprintf("%s %d %d", s.metricName(r.Name, "failed"), c[1], ts),
+			fmt.Sprintf("%s %d %d", s.metricName(r.Name, "badness"), r.Badness, ts),
+		)
+	} else {
+		counter := "failed"
+		if r.Result.Passed {
+			counter = "passed"
+		}
+		lines = append(lines,
+			fmt.Sprintf("%s:%d|ms", s.metricName(r.Name, "latency"), ms),
+			fmt.Sprintf("%s:1|c", s.metricName(r.Name, counter)),
+			fmt.Sprintf("%s:%d|g", s.metricName(r.Name, "badness"), r.Badness),
+		)
+	}
+	s.lines = append(s.lines, lines...)
+	if n := len(s.lines) - maxMetricLines; n > 0 {
+		s.lines = s.lines[n:]
+	}
+}
+
+// push sends the lines, as StatsD packets or to Graphite.
+func (s *metricsSink) push(lines []string) error {
+	if s.graphite {
+		conn, err := net.DialTimeout("tcp", s.addr, metricsTimeout)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetWriteDeadline(time.Now().Add(metricsTimeout))
+		_, err = conn.Write([]byte(strings.Join(lines, "\n") + "\n"))
+		return err
+	}
+	conn, err := net.DialTimeout("udp", s.addr, metricsTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	packet := ""
+	for _, l := range lines {
+		if packet != "" && len(packet)+1+len(l) > maxStatsdPacket {
+			if _, err := conn.Write([]byte(packet)); err != nil {
+				return err
+			}
+			packet = ""
+		}
+		if packet != "" {
+			packet += "\n"
+		}
+		packet += l
+	}
+	_, err = conn.Write([]byte(packet))
+	return err
+}
+
+// flush pushes the metrics recorded since the last push. Metrics that
+// fail to push are dropped.
+func (s *metricsSink) flush() {
+	s.Lock()
+	lines := s.lines
+	s.lines = nil
+	s.Unlock()
+	if len(lines) == 0 {
+		return
+	}
+	if err := s.push(lines); err != nil {
+		log.Printf("Failed to push %d metrics to %s: %v\n", len(lines), s.addr, err)
+	}
+}
+
+// flushLoop pushes metrics every interval, blocking until the context
+// is done, when it pushes them one last time.
+func (s *metricsSink) flushLoop(ctx context.Context) {
+	t := time.NewTicker(metricsInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush()
+			return
+		case <-t.C:
+			s.flush()
+		}
+	}
+}
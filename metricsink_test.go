@@ -0,0 +1,79 @@
+package dashboard
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestMetricsSink(t *testing.T) {
+	cases := []struct {
+		network string
+		want    []string
+	}{
+		{"udp", []string{"gomon.Custom_check.latency:", "|ms", "gomon.Custom_check.passed:1|c", "gomon.Custom_check.badness:"}},
+		{"tcp", []string{"gomon.Custom_check.latency_ms ", "gomon.Custom_check.passed 1 ", "gomon.Custom_check.failed 0 ", "gomon.Custom_check.badness "}},
+	}
+	for i, tt := range cases {
+		got := make(chan string, 1)
+		addr := ""
+		scheme := "statsd"
+		if tt.network == "udp" {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("[%d] failed to listen: %v\n", i, err)
+			}
+			defer conn.Close()
+			addr = conn.LocalAddr().String()
+			go func() {
+				b := make([]byte, maxStatsdPacket)
+				n, _, _ := conn.ReadFrom(b)
+				got <- string(b[:n])
+			}()
+		} else {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("[%d] failed to listen: %v\n", i, err)
+			}
+			defer l.Close()
+			addr, scheme = l.Addr().String(), "graphite"
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				b, _ := ioutil.ReadAll(conn)
+				got <- string(b)
+			}()
+		}
+
+		d := newTestDashboard(t, Config{Debug: true, MetricsSink: scheme + "://" + addr, MetricsPrefix: "gomon."})
+		p := prober.NewProbe(fakeProber{}, "Custom check", "Custom check")
+		if err := d.AddProbe(p); err != nil {
+			t.Fatalf("[%d] failed to add probe: %v\n", i, err)
+		}
+		if _, err := d.runNow(p, "alice"); err != nil {
+			t.Fatalf("[%d] failed to run probe: %v\n", i, err)
+		}
+		d.sinks[0].(*metricsSink).flush()
+		select {
+		case lines := <-got:
+			for _, w := range tt.want {
+				if !strings.Contains(lines, w) {
+					t.Fatalf("[%d] want %q in metrics pushed over %s, got %q\n", i, w, tt.network, lines)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("[%d] want metrics pushed over %s, got none\n", i, tt.network)
+		}
+	}
+
+	if _, err := newMetricsSink("carbon://localhost:2003", "gomon"); err == nil {
+		t.Fatalf("want error for unknown metrics sink, got none\n")
+	}
+}
@@ -0,0 +1,250 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+// sendgridURL is the SendGrid v3 API endpoint for sending mail.
+const sendgridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// notification is the data for an alert notification.
+type notification struct {
+	Name, Desc string
+	Badness    int
+	Records    prober.Records
+	Links      []link // runbooks and docs for the probe
+	AckURL     string // signed link to acknowledge the alert, if any
+	Kind       string
+	Labels     map[string]string
+	Info       string    // the info of the latest result, if any
+	Since      time.Time // when the alert was raised
+	URL        string    // the probe's page, if the external URL is set
+}
+
+// notifier sends alert notifications.
+type notifier interface {
+	Notify(to string, n notification) error
+}
+
+// logNotifier logs notifications instead of sending them.
+type logNotifier struct{}
+
+func (logNotifier) Notify(to string, n notification) error {
+	log.Printf("Would notify %s about alert for %s (badness %d)\n", to, n.Name, n.Badness)
+	return nil
+}
+
+// passiveNotifier never sends notifications, for dashboards in passive
+// mode.
+type passiveNotifier struct{}
+
+func (passiveNotifier) Notify(to string, n notification) error {
+	log.Printf("Not notifying %s about alert for %s, dashboard is passive\n", to, n.Name)
+	return nil
+}
+
+// sendgridNotifier sends notifications as email through SendGrid.
+type sendgridNotifier struct {
+	token, sender string
+	endpoint      string
+	tmpl          *template.Template
+}
+
+// Notify sends the notification as email to the recipient.
+func (s sendgridNotifier) Notify(to string, n notification) error {
+	body := bytes.Buffer{}
+	if err := s.tmpl.ExecuteTemplate(&body, "email", n); err != nil {
+		return err
+	}
+	type address struct {
+		Email string `json:"email"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	type personalization struct {
+		To []address `json:"to"`
+	}
+	msg := struct {
+		Personalizations []personalization `json:"personalizations"`
+		From             address           `json:"from"`
+		Subject          string            `json:"subject"`
+		Content          []content         `json:"content"`
+	}{
+		Personalizations: []personalization{{[]address{{to}}}},
+		From:             address{s.sender},
+		Subject:          fmt.Sprintf("[gomon] %s is alerting", n.Name),
+		Content:          []content{{"text/html", body.String()}},
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// newNotifier returns the notifier for the config.
+func newNotifier(conf Config, emailTemplate string) (notifier, error) {
+	if conf.ProberDisabled {
+		log.Printf("Starting in passive mode, no alerts will be sent..\n")
+		return passiveNotifier{}, nil
+	}
+	if conf.AlertmanagerURL != "" {
+		log.Printf("Sending any alerts to Alertmanager at %s\n", conf.AlertmanagerURL)
+		return newAlertmanagerNotifier(conf.AlertmanagerURL), nil
+	}
+	if conf.Debug {
+		log.Printf("Starting in debug mode, alerts will only be logged..")
+		return logNotifier{}, nil
+	}
+	if conf.SendgridToken == "" {
+		return nil, errors.New("no DASHBOARD_SENDGRIDTOKEN specified")
+	}
+	if conf.EmailSender == "" {
+		return nil, errors.New("no DASHBOARD_EMAILSENDER specified")
+	}
+	if conf.EmailRecipient == "" {
+		return nil, errors.New("no DASHBOARD_EMAILRECIPIENT specified")
+	}
+	if emailTemplate == "" {
+		return nil, errors.New("no email template")
+	}
+	tmpl, err := template.New("email").Parse(emailTemplate)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Sending any alert emails from %q to %q\n", conf.EmailSender, conf.EmailRecipient)
+	return sendgridNotifier{
+		token:    conf.SendgridToken,
+		sender:   conf.EmailSender,
+		endpoint: sendgridURL,
+		tmpl:     tmpl,
+	}, nil
+}
+
+// sendAlert notifies everyone so far on the probe's escalation policy
+// about its alert, unless it has been acknowledged.
+func (d *Dashboard) sendAlert(name, desc string, badness int, records prober.Records) error {
+	if d.conf.ProberDisabled {
+		return nil
+	}
+	a, raised := d.alerts.raise(name)
+	d.alerts.update(name, desc, badness, records)
+	if raised {
+		d.incidents.probeAlerting(name)
+		d.publishAlert(name)
+	}
+	if r, ok := d.notifier.(resolver); ok {
+		return d.fireAlert(r, name, desc, badness, records, raised)
+	}
+	if a.Acked() {
+		log.Printf("Not re-sending alert for %s, acknowledged by %s at %v\n", name, a.AckedBy, a.AckedAt)
+		return nil
+	}
+	if s, ok := d.silences.silenced(name); ok {
+		log.Printf("Not sending alert for %s, silenced by %s until %v\n", name, s.By, s.End)
+		return nil
+	}
+	if len(a.Escalations) == 0 {
+		return d.notifyTier(name, desc, badness, records, 0, d.getPolicy(name).Tiers[0].Targets)
+	}
+	var lastErr error
+	for _, e := range a.Escalations {
+		if err := d.notifyTargets(e.Targets, name, desc, badness, records); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// stopNotifications waits for notifications being sent to finish,
+// and stops any more from being sent.
+func (d *Dashboard) stopNotifications() {
+	d.sending.Lock()
+	defer d.sending.Unlock()
+	d.notifyStopped = true
+}
+
+// notifyTier notifies the targets in a tier of the escalation policy
+// about the probe's alert, and records the escalation.
+func (d *Dashboard) notifyTier(name, desc string, badness int, records prober.Records, tier int, targets []string) error {
+	if !d.isLeader() {
+		log.Printf("Not notifying tier %d about %s, %s isn't the leader\n", tier, name, d.instanceID)
+		return nil
+	}
+	if len(targets) == 0 {
+		log.Printf("Not notifying tier %d about %s, it has no targets\n", tier, name)
+		return nil
+	}
+	d.alerts.escalated(name, escalation{
+		Time:    time.Now(),
+		Tier:    tier,
+		Targets: targets,
+	})
+	if tier > 0 {
+		d.incidents.record(incidentEvent{
+			Kind:  "escalated",
+			Probe: name,
+			Text:  fmt.Sprintf("Escalated to tier %d: %s", tier, strings.Join(targets, ", ")),
+		})
+	}
+	if err := d.shareState(nil); err != nil {
+		log.Printf("Failed to share escalation of %s: %v\n", name, err)
+	}
+	return d.notifyTargets(targets, name, desc, badness, records)
+}
+
+// notifyTargets notifies the targets about the probe's alert,
+// returning the last error, if any.
+func (d *Dashboard) notifyTargets(targets []string, name, desc string, badness int, records prober.Records) error {
+	d.sending.RLock()
+	defer d.sending.RUnlock()
+	if d.notifyStopped {
+		log.Printf("Not notifying about %s, dashboard is stopping\n", name)
+		return nil
+	}
+	if d.lease != nil && !d.leader {
+		log.Printf("Not notifying about %s, %s isn't the leader\n", name, d.instanceID)
+		return nil
+	}
+	n := d.getNotification(name, desc, badness, d.getProbeInfo(name))
+	n.Records = records
+	var lastErr error
+	for _, to := range targets {
+		n.AckURL = d.getAckURL(d.conf.ExternalURL, name, to)
+		err := d.notifier.Notify(to, n)
+		d.notified(err)
+		if err != nil {
+			log.Printf("Failed to notify %s about %s: %v\n", to, name, err)
+			lastErr = err
+			continue
+		}
+		d.incidents.record(incidentEvent{Kind: "notified", Probe: name, Text: "Notified " + to})
+	}
+	return lastErr
+}
@@ -0,0 +1,355 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+const (
+	// otlpInterval is how often probe runs are exported over OTLP.
+	otlpInterval = time.Second * 30
+	// otlpTimeout is how long to wait for the collector to respond.
+	otlpTimeout = time.Second * 10
+	// otlpMaxSpans is how many spans are kept between exports, dropping
+	// the oldest ones beyond that.
+	otlpMaxSpans = 1000
+	// otlpScope is the instrumentation scope spans and metrics are
+	// exported under.
+	otlpScope = "hkjn.me/dashboard"
+)
+
+// traceContext identifies the span of a probe run, as in W3C Trace
+// Context.
+type traceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// newTraceContext returns a new trace context with random IDs.
+func newTraceContext() traceContext {
+	tc := traceContext{}
+	rand.Read(tc.TraceID[:])
+	rand.Read(tc.SpanID[:])
+	return tc
+}
+
+// traceparent returns the traceparent header for requests made during
+// the span.
+func (tc traceContext) traceparent() string {
+	return fmt.Sprintf("00-%x-%x-01", tc.TraceID, tc.SpanID)
+}
+
+// tracedProber is a prober whose runs can carry the trace context of
+// their span, like in requests they make.
+type tracedProber interface {
+	probeTraced(tc traceContext) prober.Result
+}
+
+// runTraced runs the prober, passing it the trace context if it takes
+// one.
+func runTraced(pr prober.Prober, tc traceContext) prober.Result {
+	if tp, ok := pr.(tracedProber); ok {
+		return tp.probeTraced(tc)
+	}
+	return pr.Probe()
+}
+
+// startTrace returns the trace context for a run of a probe, which is
+// zero unless runs are exported over OTLP.
+func (d *Dashboard) startTrace() traceContext {
+	if d.otlp == nil {
+		return traceContext{}
+	}
+	return newTraceContext()
+}
+
+// otlpAttr is an attribute of a span or data point in OTLP/JSON.
+type otlpAttr struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func stringAttr(k, v string) otlpAttr {
+	return otlpAttr{k, map[string]interface{}{"stringValue": v}}
+}
+
+func boolAttr(k string, v bool) otlpAttr {
+	return otlpAttr{k, map[string]interface{}{"boolValue": v}}
+}
+
+func intAttr(k string, v int64) otlpAttr {
+	return otlpAttr{k, map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}}
+}
+
+// unixNano returns the time in nanoseconds, as OTLP/JSON has it.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpSpan is a span in OTLP/JSON.
+type otlpSpan struct {
+	TraceID    string     `json:"traceId"`
+	SpanID     string     `json:"spanId"`
+	Name       string     `json:"name"`
+	Kind       int        `json:"kind"`
+	Start      string     `json:"startTimeUnixNano"`
+	End        string     `json:"endTimeUnixNano"`
+	Attributes []otlpAttr `json:"attributes"`
+	Status     struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+// otlpStats are the metrics of a probe, as exported over OTLP.
+type otlpStats struct {
+	kind           string
+	passed, failed int64
+	up             bool
+	duration       time.Duration // of the latest run
+	badness        int
+}
+
+// otlpExporter exports probe runs to an OpenTelemetry collector over
+// OTLP/HTTP, as spans and metrics.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	resource []otlpAttr
+	start    time.Time // when cumulative metrics started counting
+
+	sync.Mutex
+	spans []otlpSpan
+	stats map[string]*otlpStats // by probe name
+}
+
+// newOTLPExporter returns an exporter posting to the collector at the
+// base URL, with the headers.
+func newOTLPExporter(baseURL string, headers map[string]string, instanceID string) *otlpExporter {
+	return &otlpExporter{
+		endpoint: strings.TrimSuffix(baseURL, "/"),
+		headers:  headers,
+		client:   &http.Client{Timeout: otlpTimeout},
+		resource: []otlpAttr{
+			stringAttr("service.name", "gomon"),
+			stringAttr("service.instance.id", instanceID),
+		},
+		start: time.Now(),
+		stats: map[string]*otlpStats{},
+	}
+}
+
+// record adds a span for the run, and counts it in the metrics.
+func (e *otlpExporter) record(r probeRun) {
+	s := otlpSpan{
+		TraceID: hex.EncodeToString(r.Trace.TraceID[:]),
+		SpanID:  hex.EncodeToString(r.Trace.SpanID[:]),
+		Name:    "probe " + r.Name,
+		Kind:    3, // SPAN_KIND_CLIENT
+		Start:   unixNano(r.Start),
+		End:     unixNano(r.Start.Add(r.Duration)),
+		Attributes: []otlpAttr{
+			stringAttr("probe.name", r.Name),
+			stringAttr("probe.kind", r.Info.Kind),
+			stringAttr("probe.target", r.Info.Target),
+			boolAttr("probe.passed", r.Result.Passed),
+			stringAttr("probe.info", r.Result.Info),
+			intAttr("probe.badness", int64(r.Badness)),
+			intAttr("probe.duration_ms", r.Duration.Milliseconds()),
+		},
+	}
+	for _, k := range sortedKeys(r.Info.Labels) {
+		s.Attributes = append(s.Attributes, stringAttr("probe.label."+k, r.Info.Labels[k]))
+	}
+	if r.Result.Passed {
+		s.Status.Code = 1 // STATUS_CODE_OK
+	} else {
+		s.Status.Code, s.Status.Message = 2, r.Result.Info // STATUS_CODE_ERROR
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if len(e.spans) >= otlpMaxSpans {
+		e.spans = e.spans[1:]
+	}
+	e.spans = append(e.spans, s)
+	st, ok := e.stats[r.Name]
+	if !ok {
+		st = &otlpStats{}
+		e.stats[r.Name] = st
+	}
+	st.kind, st.up, st.duration, st.badness = r.Info.Kind, r.Result.Passed, r.Duration, r.Badness
+	if r.Result.Passed {
+		st.passed++
+	} else {
+		st.failed++
+	}
+}
+
+// sortedKeys returns the keys of the map, sorted.
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metrics returns the metrics of all probes at the time, in OTLP/JSON.
+func (e *otlpExporter) metrics(now time.Time) []interface{} {
+	runs, durations, ups, badnesses := []interface{}{}, []interface{}{}, []interface{}{}, []interface{}{}
+	names := []string{}
+	for name := range e.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		st := e.stats[name]
+		attrs := []otlpAttr{stringAttr("probe", name), stringAttr("kind", st.kind)}
+		for _, c := range []struct {
+			result string
+			n      int64
+		}{{"passed", st.passed}, {"failed", st.failed}} {
+			runs = append(runs, map[string]interface{}{
+				"attributes":        append(attrs[:2:2], stringAttr("result", c.result)),
+				"startTimeUnixNano": unixNano(e.start),
+				"timeUnixNano":      unixNano(now),
+				"asInt":             strconv.FormatInt(c.n, 10),
+			})
+		}
+		durations = append(durations, map[string]interface{}{
+			"attributes":   attrs,
+			"timeUnixNano": unixNano(now),
+			"asDouble":     st.duration.Seconds(),
+		})
+		up := int64(0)
+		if st.up {
+			up = 1
+		}
+		ups = append(ups, map[string]interface{}{
+			"attributes":   attrs,
+			"timeUnixNano": unixNano(now),
+			"asInt":        strconv.FormatInt(up, 10),
+		})
+		badnesses = append(badnesses, map[string]interface{}{
+			"attributes":   attrs,
+			"timeUnixNano": unixNano(now),
+			"asInt":        strconv.Itoa(st.badness),
+		})
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"name":        "gomon.probe.runs",
+			"description": "Runs of the probe, by result.",
+			"unit":        "1",
+			"sum": map[string]interface{}{
+				"aggregationTemporality": 2, // AGGREGATION_TEMPORALITY_CUMULATIVE
+				"isMonotonic":            true,
+				"dataPoints":             runs,
+			},
+		},
+		map[string]interface{}{
+			"name":        "gomon.probe.duration",
+			"description": "How long the latest run of the probe took.",
+			"unit":        "s",
+			"gauge":       map[string]interface{}{"dataPoints": durations},
+		},
+		map[string]interface{}{
+			"name":        "gomon.probe.up",
+			"description": "Whether the latest run of the probe passed.",
+			"unit":        "1",
+			"gauge":       map[string]interface{}{"dataPoints": ups},
+		},
+		map[string]interface{}{
+			"name":        "gomon.probe.badness",
+			"description": "How bad it is when the probe fails.",
+			"unit":        "1",
+			"gauge":       map[string]interface{}{"dataPoints": badnesses},
+		},
+	}
+}
+
+// post sends the spans or metrics, under the key, to the collector's
+// path.
+func (e *otlpExporter) post(path, resourceKey, scopeKey, itemsKey string, items interface{}) error {
+	payload := map[string]interface{}{
+		resourceKey: []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": e.resource},
+			scopeKey: []interface{}{map[string]interface{}{
+				"scope":  map[string]interface{}{"name": otlpScope},
+				itemsKey: items,
+			}},
+		}},
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// flush exports the spans since the last export, and the metrics so
+// far. Spans that fail to export are dropped.
+func (e *otlpExporter) flush() {
+	e.Lock()
+	spans := e.spans
+	e.spans = nil
+	metrics := e.metrics(time.Now())
+	e.Unlock()
+
+	if len(spans) > 0 {
+		if err := e.post("/v1/traces", "resourceSpans", "scopeSpans", "spans", spans); err != nil {
+			log.Printf("Failed to export %d spans: %v\n", len(spans), err)
+		}
+	}
+	if err := e.post("/v1/metrics", "resourceMetrics", "scopeMetrics", "metrics", metrics); err != nil {
+		log.Printf("Failed to export metrics: %v\n", err)
+	}
+}
+
+// flushLoop exports probe runs every interval, blocking until the
+// context is done, when it exports them one last time.
+func (e *otlpExporter) flushLoop(ctx context.Context) {
+	t := time.NewTicker(otlpInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.flush()
+			return
+		case <-t.C:
+			e.flush()
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"hkjn.me/prober"
+)
+
+func TestOTLP(t *testing.T) {
+	lock := sync.Mutex{}
+	exported := map[string]string{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil || r.Header.Get("Authorization") != "Bearer secret" || !json.Valid(b) {
+			http.Error(w, "bad export", http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		exported[r.URL.Path] = string(b)
+	}))
+	defer collector.Close()
+	targetLock := sync.Mutex{}
+	traceparent := ""
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetLock.Lock()
+		defer targetLock.Unlock()
+		traceparent = r.Header.Get("traceparent")
+		fmt.Fprint(w, "welcome\n")
+	}))
+	defer target.Close()
+
+	d := newTestDashboard(t, Config{
+		Debug:        true,
+		OTLPEndpoint: collector.URL + "/",
+		OTLPHeaders:  map[string]string{"Authorization": "Bearer secret"},
+	})
+	p := prober.NewProbe(fakeProber{}, "Custom", "Custom check")
+	if err := d.AddProbe(p, Labels(map[string]string{"team": "ops"})); err != nil {
+		t.Fatalf("failed to add probe: %v\n", err)
+	}
+	if _, err := d.runNow(p, "alice"); err != nil {
+		t.Fatalf("failed to run probe: %v\n", err)
+	}
+	probecfg := probeConfig{}
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf("webprobes:\n  - name: WebIndex\n    target: %s/\n    wantstatus: 200\n    want: welcome\n", target.URL)), &probecfg); err != nil {
+		t.Fatalf("failed to parse config: %v\n", err)
+	}
+	l := newProbeLoader(probecfg)
+	l.webClient = d.webClient
+	wp := l.loadProbes()[0]
+	if err := d.AddProbe(wp); err != nil {
+		t.Fatalf("failed to add probe: %v\n", err)
+	}
+	if r, err := d.runNow(wp, "alice"); err != nil || !r.Passed {
+		t.Fatalf("want web probe passing, got %+v, %v\n", r, err)
+	}
+	if _, ok := http.DefaultTransport.(*http.Transport); !ok {
+		t.Fatalf("want default transport untouched, got %T\n", http.DefaultTransport)
+	}
+	targetLock.Lock()
+	parts := strings.Split(traceparent, "-")
+	targetLock.Unlock()
+	if len(parts) != 4 {
+		t.Fatalf("want traceparent on web probe request, got %q\n", traceparent)
+	}
+	d.otlp.flush()
+
+	lock.Lock()
+	defer lock.Unlock()
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{"/v1/traces", []string{`"name":"probe Custom"`, `"stringValue":"custom"`, `"key":"probe.label.team"`, `"code":1`, `"traceId":"` + parts[1] + `"`, `"spanId":"` + parts[2] + `"`}},
+		{"/v1/metrics", []string{`"name":"gomon.probe.runs"`, `"asInt":"1"`, `"name":"gomon.probe.duration"`, `"stringValue":"gomon"`}},
+	}
+	for i, tt := range cases {
+		got, ok := exported[tt.path]
+		if !ok {
+			t.Fatalf("[%d] want export to %s, got none\n", i, tt.path)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Fatalf("[%d] want %s in export to %s, got %s\n", i, w, tt.path, got)
+			}
+		}
+	}
+}
+
+func TestWebProbes(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/index", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "welcome\n")
+	}))
+	defer target.Close()
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+
+	cases := []struct {
+		path       string
+		wantStatus int
+		want       string
+		wantPassed bool
+	}{
+		{"/", 302, "/index", true},
+		{"/", 200, "welcome", false},
+		{"/index", 200, "welcome", true},
+		{"/index", 200, "goodbye", false},
+		{"/index", 404, "", false},
+	}
+	for i, tt := range cases {
+		results := []ProbeResult{}
+		for _, conf := range []Config{{Debug: true}, {Debug: true, OTLPEndpoint: collector.URL}} {
+			d := newTestDashboard(t, conf)
+			probecfg := probeConfig{}
+			if err := yaml.Unmarshal([]byte(fmt.Sprintf("webprobes:\n  - name: Web\n    target: %s%s\n    wantstatus: %d\n    want: %q\n", target.URL, tt.path, tt.wantStatus, tt.want)), &probecfg); err != nil {
+				t.Fatalf("[%d] failed to parse config: %v\n", i, err)
+			}
+			l := newProbeLoader(probecfg)
+			l.webClient = d.webClient
+			p := l.loadProbes()[0]
+			if err := d.AddProbe(p); err != nil {
+				t.Fatalf("[%d] failed to add probe: %v\n", i, err)
+			}
+			r, err := d.runNow(p, "alice")
+			if err != nil {
+				t.Fatalf("[%d] failed to run probe: %v\n", i, err)
+			}
+			results = append(results, r)
+		}
+		if results[0].Passed != tt.wantPassed || results[0].Info != results[1].Info || results[0].Passed != results[1].Passed {
+			t.Fatalf("[%d] want web probe passing %v with OTLP on and off alike, got %+v and %+v\n", i, tt.wantPassed, results[0], results[1])
+		}
+	}
+}
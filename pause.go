@@ -0,0 +1,256 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+const (
+	// pauseCheckInterval is how often expired pauses are ended.
+	pauseCheckInterval = time.Second * 10
+	// defaultPause is how long probes are paused from the index page
+	// if no duration is given.
+	defaultPause = time.Hour
+)
+
+// errPassive is returned when asked to run probes on a passive
+// dashboard.
+var errPassive = errors.New("dashboard is passive, probes don't run")
+
+// pausedResult is the result of paused probes, if they're run anyway.
+var pausedResult = prober.Result{Passed: true, Info: "probe paused"}
+
+// Pause describes why and until when a probe is paused.
+type Pause struct {
+	By     string    `json:"by"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+}
+
+// pauseBook tracks the paused probes.
+type pauseBook struct {
+	sync.Mutex
+	pauses map[string]Pause // by probe name
+}
+
+// newPauseBook returns a new pause book, with no probes paused.
+func newPauseBook() *pauseBook {
+	return &pauseBook{pauses: map[string]Pause{}}
+}
+
+// get returns the pause of the probe, if it's paused.
+func (b *pauseBook) get(name string) (Pause, bool) {
+	b.Lock()
+	defer b.Unlock()
+	p, ok := b.pauses[name]
+	return p, ok
+}
+
+// expired removes and returns the names of probes whose pause has
+// ended by the time.
+func (b *pauseBook) expired(t time.Time) []string {
+	b.Lock()
+	defer b.Unlock()
+	names := []string{}
+	for name, p := range b.pauses {
+		if !t.Before(p.Until) {
+			delete(b.pauses, name)
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// pause stops running the probe until resumed or the duration has
+// passed.
+func (d *Dashboard) pause(name, by, reason string, duration time.Duration) error {
+	if by == "" {
+		return errors.New("no name given for pause")
+	}
+	if reason == "" {
+		return errors.New("no reason given for pause")
+	}
+	if duration <= 0 {
+		return errors.New("pause must last a while")
+	}
+	p := d.findProbe(name)
+	if p == nil {
+		return errNotFound
+	}
+	now := time.Now()
+	d.pauses.Lock()
+	d.pauses.pauses[name] = Pause{by, reason, now, now.Add(duration)}
+	d.pauses.Unlock()
+	log.Printf("Probe %s paused by %s until %v: %s\n", name, by, now.Add(duration), reason)
+	d.publishAlert(name)
+	return nil
+}
+
+// resume runs the probe again after it was paused.
+func (d *Dashboard) resume(name, by string) error {
+	d.pauses.Lock()
+	_, ok := d.pauses.pauses[name]
+	delete(d.pauses.pauses, name)
+	d.pauses.Unlock()
+	if !ok {
+		return fmt.Errorf("probe %s isn't paused", name)
+	}
+	log.Printf("Probe %s resumed by %s\n", name, by)
+	d.publishAlert(name)
+	return nil
+}
+
+// resumeLoop repeatedly resumes probes whose pause has ended, blocking
+// until the context is done.
+func (d *Dashboard) resumeLoop(ctx context.Context) {
+	t := time.NewTicker(pauseCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			for _, name := range d.pauses.expired(now) {
+				log.Printf("Pause of probe %s ended\n", name)
+				d.publishAlert(name)
+			}
+		}
+	}
+}
+
+// runNow runs the probe right away, outside of its schedule and even
+// if it's paused, recording the result.
+//
+// The result is also added to the probe's own records, and a passing
+// run clears its badness, so a probe that's fixed recovers right away
+// rather than at its next scheduled run. A failing run leaves the
+// badness alone, so running a probe by hand never raises an alert
+// sooner than its schedule would.
+func (d *Dashboard) runNow(p *prober.Probe, who string) (ProbeResult, error) {
+	if d.conf.ProberDisabled {
+		return ProbeResult{}, errPassive
+	}
+	log.Printf("Running %s at the request of %s\n", p.Name, who)
+	start := time.Now()
+	tp, tracked := p.Prober.(trackedProber)
+	var r prober.Result
+	if tracked {
+		r = tp.run()
+	} else {
+		r = p.Prober.Probe()
+	}
+	res := ProbeResult{p.Name, start, r.Passed, r.Info, time.Since(start)}
+	if r == stoppedResult || r == removedResult {
+		return res, nil
+	}
+	p.Records = append(p.Records, &prober.Record{Timestamp: time.Now(), Result: r})
+	if r.Passed {
+		p.Badness = 0
+	}
+	if info := d.getProbeInfo(p.Name); tracked && len(info.Locations) == 0 {
+		tp.checkRecovered(info)
+	}
+	return res, nil
+}
+
+// canAct returns true if the viewer of the request may run, pause and
+// resume probes from the index page, which they may only if they have
+// one of the configured action roles and the dashboard isn't passive.
+func (d *Dashboard) canAct(r *http.Request) bool {
+	if d.conf.ProberDisabled {
+		return false
+	}
+	for _, want := range d.conf.ActionRoles {
+		for _, role := range d.getViewerRoles(r) {
+			if role == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// csrfToken returns the token the forms acting on probes must send for
+// the viewer of the request, so other sites can't make a viewer's
+// browser act on their behalf. As for ack links, it's signed with the
+// ack secret.
+func (d *Dashboard) csrfToken(r *http.Request) string {
+	who := d.getViewer(r)
+	mac := hmac.New(sha256.New, d.ackSecret)
+	fmt.Fprintf(mac, "csrf:%d:%s", len(who), who)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// withActionRoles returns a handler only allowing viewers who can act
+// on probes, from forms carrying their CSRF token.
+func (d *Dashboard) withActionRoles(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.canAct(r) {
+			http.Error(w, "You may not act on probes.", http.StatusForbidden)
+			return
+		}
+		if !hmac.Equal([]byte(r.FormValue("csrf")), []byte(d.csrfToken(r))) {
+			http.Error(w, "Bad or missing CSRF token, reload the page and try again.", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// runFromForm runs a probe from the form on the index page.
+func (d *Dashboard) runFromForm(w http.ResponseWriter, r *http.Request) {
+	p := d.findProbe(getProbeName(r))
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := d.runNow(p, d.getViewer(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, d.conf.HttpPrefix+"/#"+p.Name, http.StatusSeeOther)
+}
+
+// pauseFromForm pauses a probe from the form on the index page.
+func (d *Dashboard) pauseFromForm(w http.ResponseWriter, r *http.Request) {
+	name := getProbeName(r)
+	duration := defaultPause
+	if v := r.FormValue("duration"); v != "" {
+		var err error
+		if duration, err = time.ParseDuration(v); err != nil {
+			http.Error(w, "Bad duration.", http.StatusBadRequest)
+			return
+		}
+	}
+	err := d.pause(name, d.getViewer(r), r.FormValue("reason"), duration)
+	if err == errNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, d.conf.HttpPrefix+"/#"+name, http.StatusSeeOther)
+}
+
+// resumeFromForm resumes a probe from the form on the index page.
+func (d *Dashboard) resumeFromForm(w http.ResponseWriter, r *http.Request) {
+	name := getProbeName(r)
+	who := d.getViewer(r)
+	if err := d.resume(name, who); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, d.conf.HttpPrefix+"/#"+name, http.StatusSeeOther)
+}
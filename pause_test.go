@@ -0,0 +1,116 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestPauseResume(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true, RolesHeader: "X-Roles", ActionRoles: []string{"oncall"}})
+	p := prober.NewProbe(resultProber{Info: "down"}, "WebIndex", "")
+	d.AddProbe(p)
+
+	if err := d.pause("WebIndex", "alice", "", time.Hour); err == nil {
+		t.Fatalf("want error pausing without a reason, got none\n")
+	}
+	if err := d.pause("Missing", "alice", "fixing", time.Hour); err != errNotFound {
+		t.Fatalf("want errNotFound pausing missing probe, got %v\n", err)
+	}
+	if err := d.pause("WebIndex", "alice", "fixing", time.Hour); err != nil {
+		t.Fatalf("failed to pause: %v\n", err)
+	}
+	if got := d.getProbeStatus(p); !got.Disabled || got.Pause == nil || p.Disabled {
+		t.Fatalf("want paused probe shown as disabled, without disabling the probe itself, got %+v\n", got)
+	}
+	if got := p.Prober.Probe(); got != pausedResult {
+		t.Fatalf("want %+v from paused probe, got %+v\n", pausedResult, got)
+	}
+	if got, err := d.runNow(p, "alice"); err != nil || got.Passed || got.Info != "down" {
+		t.Fatalf("want run now of paused probe to run it, got %+v, %v\n", got, err)
+	}
+	if got := d.pauses.expired(time.Now().Add(2 * time.Hour)); len(got) != 1 || got[0] != "WebIndex" {
+		t.Fatalf("want pause of WebIndex expired, got %v\n", got)
+	}
+
+	cases := []struct {
+		roles  string
+		action string
+		form   url.Values
+		want   int
+	}{
+		{"", "pause", url.Values{"by": {"bob"}, "reason": {"deploy"}}, http.StatusForbidden},
+		{"viewer", "run", nil, http.StatusForbidden},
+		{"oncall", "pause", url.Values{"by": {"bob"}, "reason": {"deploy"}, "duration": {"10m"}}, http.StatusSeeOther},
+		{"oncall", "pause", url.Values{"by": {"bob"}, "reason": {"deploy"}, "duration": {"soon"}}, http.StatusBadRequest},
+		{"oncall", "resume", url.Values{"by": {"bob"}}, http.StatusSeeOther},
+		{"oncall", "resume", url.Values{"by": {"bob"}}, http.StatusConflict},
+		{"oncall", "run", nil, http.StatusSeeOther},
+	}
+	for i, tt := range cases {
+		if tt.form == nil {
+			tt.form = url.Values{}
+		}
+		tt.form.Set("csrf", csrfFor(d, ""))
+		r := httptest.NewRequest("POST", "/probes/WebIndex/"+tt.action, strings.NewReader(tt.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-Roles", tt.roles)
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Fatalf("[%d] want %d from %s as %q, got %d: %s\n", i, tt.want, tt.action, tt.roles, w.Code, w.Body)
+		}
+	}
+}
+
+func TestRunNow(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true, RolesHeader: "X-Roles", ActionRoles: []string{"oncall"}})
+	p := prober.NewProbe(resultProber{Passed: true, Info: "up"}, "WebIndex", "")
+	d.AddProbe(p)
+	p.Badness = 80
+
+	if got, err := d.runNow(p, "alice"); err != nil || !got.Passed {
+		t.Fatalf("want passing run now, got %+v, %v\n", got, err)
+	}
+	if len(p.Records) != 1 || p.Records[0].Result.Info != "up" || p.Badness != 0 {
+		t.Fatalf("want run now added to records and badness cleared, got %d records, badness %d\n", len(p.Records), p.Badness)
+	}
+
+	tp := p.Prober.(trackedProber)
+	tp.Prober = resultProber{Info: "down"}
+	p.Prober = tp
+	p.Badness = 40
+	if got, err := d.runNow(p, "alice"); err != nil || got.Passed {
+		t.Fatalf("want failing run now, got %+v, %v\n", got, err)
+	}
+	if len(p.Records) != 2 || p.Badness != 40 {
+		t.Fatalf("want failing run now added to records with badness untouched, got %d records, badness %d\n", len(p.Records), p.Badness)
+	}
+
+	r := httptest.NewRequest("POST", "/probes/WebIndex/run", strings.NewReader("csrf="+csrfFor(d, "")))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Roles", "oncall")
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, r)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("want %d running from form, got %d: %s\n", http.StatusSeeOther, w.Code, w.Body)
+	}
+	if len(p.Records) != 3 {
+		t.Fatalf("want run from form done before redirecting, got %d records\n", len(p.Records))
+	}
+}
+
+// csrfFor returns the CSRF token of the user, or of anyone if the
+// dashboard doesn't know its viewers.
+func csrfFor(d *Dashboard, user string) string {
+	r := httptest.NewRequest("GET", "/", nil)
+	if d.conf.UserHeader != "" {
+		r.Header.Set(d.conf.UserHeader, user)
+	}
+	return d.csrfToken(r)
+}
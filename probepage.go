@@ -0,0 +1,79 @@
+package dashboard
+
+import (
+	"net/http"
+	"strconv"
+
+	"hkjn.me/prober"
+)
+
+// resultsPerPage is how many results are shown per page of history.
+const resultsPerPage = 50
+
+var probeTmpls = append(
+	baseTmpls,
+	"tmpl/probe.tmpl",
+)
+
+// findProbe returns the probe with the name, or nil.
+func (d *Dashboard) findProbe(name string) *prober.Probe {
+	for _, p := range d.listProbes() {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// getProbeData returns the data for the page of a single probe.
+func (d *Dashboard) getProbeData(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	p := d.findProbe(getProbeName(r))
+	if p == nil {
+		return nil, errNotFound
+	}
+	page, err := strconv.Atoi(r.FormValue("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	_, total := d.history.results(p.Name, 0, 0)
+	if last := total/resultsPerPage + 1; page > last {
+		page = last
+	}
+	data := struct {
+		Probe       probeView
+		Latency     latencyStats
+		Results     []result
+		Page        int
+		PrevPage    int
+		NextPage    int
+		Incidents   []incident
+		Transitions []incidentEvent
+	}{
+		Probe:   d.view(p, r),
+		Latency: d.history.latency(p.Name),
+		Page:    page,
+	}
+	data.Results, total = d.history.results(p.Name, (page-1)*resultsPerPage, resultsPerPage)
+	if page > 1 {
+		data.PrevPage = page - 1
+	}
+	if page*resultsPerPage < total {
+		data.NextPage = page + 1
+	}
+	for _, i := range d.incidents.list() {
+		affected := false
+		for _, name := range i.Probes {
+			affected = affected || name == p.Name
+		}
+		if !affected {
+			continue
+		}
+		data.Incidents = append(data.Incidents, i)
+		for _, e := range i.Events {
+			if e.Probe == p.Name && e.Kind != "notified" {
+				data.Transitions = append(data.Transitions, e)
+			}
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,35 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestProbePage(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true})
+	d.AddProbe(prober.NewProbe(fakeProber{}, "WebIndex", ""))
+	for i := 0; i < resultsPerPage+1; i++ {
+		d.history.record("WebIndex", prober.Result{Passed: true}, time.Now(), time.Second)
+	}
+	cases := []struct {
+		page     string
+		wantCode int
+	}{
+		{"", http.StatusOK},
+		{"2", http.StatusOK},
+		{"-1", http.StatusOK},
+		{"200000000000000000", http.StatusOK},
+		{"9223372036854775807", http.StatusOK},
+	}
+	for i, tt := range cases {
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, httptest.NewRequest("GET", "/probes/WebIndex?page="+tt.page, nil))
+		if w.Code != tt.wantCode {
+			t.Fatalf("[%d] want HTTP response %d for page %q, got %d: %s\n", i, tt.wantCode, tt.page, w.Code, w.Body.String())
+		}
+	}
+}
@@ -1,90 +1,385 @@
 package dashboard
 
 import (
-	"flag"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
 	"sort"
-	"sync"
+	"strings"
 	"time"
 
 	"hkjn.me/prober"
 	"hkjn.me/probes/dnsprobe"
 	"hkjn.me/probes/varsprobe"
-	"hkjn.me/probes/webprobe"
 )
 
-// TODO(hkjn): Add support for sending POST requests in webprobe.
-var (
-	proberDisabled = flag.Bool("no_probes", false, "disables probes")
-	allProbes      = prober.Probes{}
-	createOnce     = sync.Once{}
+// TODO(hkjn): Add support for sending POST requests in web probes.
+
+const (
+	// webProbeTimeout is how long web probes wait for a response.
+	webProbeTimeout = time.Second * 30
+	// webProbeMaxBody is how much of the response web probes search.
+	webProbeMaxBody = 1 << 20
 )
 
+// probeInfo describes how a probe was configured.
+type probeInfo struct {
+	Kind   string   // "web", "vars" or "dns"
+	Target string   // what the probe checks
+	Expect []string // what the probe expects, in words
+	Links  []link   // runbooks and docs for the probe
+	// Labels are arbitrary key-value pairs describing the probe, like
+	// the team owning it.
+	Labels map[string]string
+	// Locations are where the probe runs, by the dashboard itself as
+	// "central" or by agents, if anywhere but the dashboard.
+	Locations []string
+	// Quorum is how many locations must fail for the probe to alert,
+	// or 0 for a majority of them.
+	Quorum int
+}
+
+// probeLoader creates the probes in a config, noting how each was
+// configured.
+type probeLoader struct {
+	probecfg  probeConfig
+	infos     map[string]probeInfo // how each probe was configured, by name
+	policies  map[string]string    // escalation policy names, by probe name
+	webClient *http.Client         // client running the web probes
+}
+
+// newProbeLoader returns a new loader for the probes in the config,
+// with a client of its own for web probes.
+func newProbeLoader(probecfg probeConfig) *probeLoader {
+	return &probeLoader{probecfg: probecfg, infos: map[string]probeInfo{}, policies: map[string]string{}, webClient: newWebClient()}
+}
+
 // getWebProbes returns the web probes.
-func getWebProbes() prober.Probes {
+func (l *probeLoader) getWebProbes() prober.Probes {
 	probes := prober.Probes{}
-	for _, p := range probecfg.WebProbes {
-		wp := webprobe.NewWithGeneric(
-			p.Target,
-			"GET",
-			p.WantStatus,
-			[]prober.Option{prober.Interval(time.Minute * 2)},
-			webprobe.Name(p.Name),
-			webprobe.InResponse(p.Want))
+	for _, p := range l.probecfg.WebProbes {
+		wp := prober.NewProbe(
+			webProber{p.Target, p.WantStatus, p.Want, l.webClient},
+			p.Name,
+			"GET "+p.Target,
+			prober.Interval(time.Minute*2))
+		l.setPolicy(wp.Name, p.Escalation)
+		expect := []string{fmt.Sprintf("Status %d", p.WantStatus)}
+		if p.Want != "" {
+			expect = append(expect, fmt.Sprintf("Response contains %q", p.Want))
+		}
+		l.infos[wp.Name] = probeInfo{"web", p.Target, expect, p.Links, nil, p.Locations, p.Quorum}
 		probes = append(probes, wp)
 	}
 	return probes
 }
 
+// webProber requests a URL, passing if it responds with the status
+// and, if set, a body containing the string. Redirects aren't
+// followed, so probes can check them. When tracing runs, requests
+// carry the traceparent header of the run.
+type webProber struct {
+	target     string
+	wantStatus int
+	want       string
+	client     *http.Client
+}
+
+// newWebClient returns the client for web probes, with its own
+// transport, not following redirects.
+func newWebClient() *http.Client {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = t.Clone()
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webProbeTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Probe requests the URL, outside of any trace.
+func (p webProber) Probe() prober.Result {
+	return p.probeTraced(traceContext{})
+}
+
+// probeTraced requests the URL, carrying the trace context unless
+// it's zero.
+func (p webProber) probeTraced(tc traceContext) prober.Result {
+	req, err := http.NewRequest("GET", p.target, nil)
+	if err != nil {
+		return prober.Result{Info: fmt.Sprintf("bad target: %v", err)}
+	}
+	if tc != (traceContext{}) {
+		req.Header.Set("traceparent", tc.traceparent())
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return prober.Result{Info: err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != p.wantStatus {
+		return prober.Result{Info: fmt.Sprintf("got status %d, want %d", resp.StatusCode, p.wantStatus)}
+	}
+	if p.want != "" {
+		b, err := ioutil.ReadAll(io.LimitReader(resp.Body, webProbeMaxBody))
+		if err != nil {
+			return prober.Result{Info: fmt.Sprintf("failed to read response: %v", err)}
+		}
+		if !strings.Contains(string(b), p.want) {
+			return prober.Result{Info: fmt.Sprintf("response doesn't contain %q", p.want)}
+		}
+	}
+	return prober.Result{Passed: true, Info: fmt.Sprintf("got status %d", resp.StatusCode)}
+}
+
+// Alert does nothing, as alerts of tracked probes go through the
+// dashboard.
+func (p webProber) Alert(name, desc string, badness int, records prober.Records) error {
+	return nil
+}
+
 // getVarsProbes returns the vars probes.
-func getVarsProbes() prober.Probes {
+func (l *probeLoader) getVarsProbes() prober.Probes {
 	probes := prober.Probes{}
-	for _, p := range probecfg.VarsProbes {
-		probes = append(probes,
-			varsprobe.New(
-				p.Target,
-				varsprobe.Name(p.Name),
-				varsprobe.Key(p.Key),
-				varsprobe.WantValue(p.WantValue),
-			))
+	for _, p := range l.probecfg.VarsProbes {
+		vp := varsprobe.New(
+			p.Target,
+			varsprobe.Name(p.Name),
+			varsprobe.Key(p.Key),
+			varsprobe.WantValue(p.WantValue),
+		)
+		l.setPolicy(vp.Name, p.Escalation)
+		l.infos[vp.Name] = probeInfo{
+			"vars",
+			p.Target,
+			[]string{fmt.Sprintf("%s is %q", p.Key, p.WantValue)},
+			p.Links,
+			nil,
+			p.Locations,
+			p.Quorum,
+		}
+		probes = append(probes, vp)
 	}
 	return probes
 }
 
 // getDnsProbes returns the dns probes.
-func getDnsProbes() prober.Probes {
+func (l *probeLoader) getDnsProbes() prober.Probes {
 	probes := prober.Probes{}
-	for _, p := range probecfg.DnsProbes {
+	for _, pc := range l.probecfg.DnsProbes {
 		mxRecords := []*net.MX{}
-		for _, mx := range p.Records.Mx {
+		for _, mx := range pc.Records.Mx {
 			mxRecords = append(mxRecords, &net.MX{
 				Host: mx.Host,
 				Pref: mx.Pref,
 			})
 		}
 		nsRecords := []*net.NS{}
-		for _, ns := range p.Records.Ns {
+		for _, ns := range pc.Records.Ns {
 			nsRecords = append(nsRecords, &net.NS{Host: ns})
 		}
 		p := dnsprobe.New(
-			p.Target,
+			pc.Target,
 			dnsprobe.MX(mxRecords),
-			dnsprobe.A(p.Records.A),
+			dnsprobe.A(pc.Records.A),
 			dnsprobe.NS(nsRecords),
-			dnsprobe.CNAME(p.Records.Cname),
-			dnsprobe.TXT(p.Records.Txt))
+			dnsprobe.CNAME(pc.Records.Cname),
+			dnsprobe.TXT(pc.Records.Txt))
 		log.Printf("adding dnsprobe: %v\n", p)
+		l.setPolicy(p.Name, pc.Escalation)
+		l.infos[p.Name] = probeInfo{
+			"dns",
+			pc.Target,
+			getDnsExpectations(pc.Records.Cname, pc.Records.A, mxRecords, nsRecords, pc.Records.Txt),
+			pc.Links,
+			nil,
+			pc.Locations,
+			pc.Quorum,
+		}
 		probes = append(probes, p)
 	}
 	return probes
 }
 
-// getProbes returns all probes in the dashboard.
-func getProbes() prober.Probes {
-	createOnce.Do(func() {
-		allProbes = append(getDnsProbes(), getWebProbes()...)
-	})
-	sort.Sort(allProbes)
-	return allProbes
+// setPolicy sets the name of the escalation policy for the probe, if
+// it names one.
+func (l *probeLoader) setPolicy(probe, policy string) {
+	if policy != "" {
+		l.policies[probe] = policy
+	}
+}
+
+var (
+	// stoppedResult is the result of probes once the dashboard is
+	// stopping.
+	//
+	// prober.Probe.Run can't be stopped, so probes keep being
+	// scheduled after the dashboard stops, but they no longer do
+	// anything.
+	stoppedResult = prober.Result{Passed: true, Info: "dashboard stopped"}
+	// removedResult is the result of probes once they've been removed
+	// from the dashboard, for the same reason.
+	removedResult = prober.Result{Passed: true, Info: "probe removed"}
+)
+
+// trackedProber wraps a prober so its results and alerts go through
+// the dashboard.
+type trackedProber struct {
+	prober.Prober
+	probe *prober.Probe
+	d     *Dashboard
+}
+
+// Probe runs the underlying prober and records the result, clearing
+// any alert once the probe has recovered.
+//
+// Once the dashboard is stopping, Probe no longer runs the underlying
+// prober, and abandons any run in flight. Neither does it once the
+// probe has been removed, or while it's paused.
+func (p trackedProber) Probe() prober.Result {
+	if _, ok := p.d.pauses.get(p.probe.Name); ok {
+		return pausedResult
+	}
+	return p.run()
+}
+
+// run runs the underlying prober, as for Probe but even if the probe
+// is paused.
+func (p trackedProber) run() prober.Result {
+	if p.d.lifetime.Err() != nil {
+		return stoppedResult
+	}
+	if !p.d.registered(p.probe) {
+		return removedResult
+	}
+	info := p.d.getProbeInfo(p.probe.Name)
+	located := len(info.Locations) > 0
+	if !located {
+		p.checkRecovered(info)
+	}
+	tc := p.d.startTrace()
+	start := time.Now()
+	c := make(chan prober.Result, 1)
+	go func() { c <- runTraced(p.Prober, tc) }()
+	var r prober.Result
+	select {
+	case r = <-c:
+	case <-p.d.lifetime.Done():
+		log.Printf("Abandoning run of %s, dashboard is stopping\n", p.probe.Name)
+		return stoppedResult
+	}
+	if pp, ok := p.Prober.(pushedProber); !ok || !pp.isPushed(r) {
+		p.d.recordRun(probeRun{
+			Name:     p.probe.Name,
+			Info:     info,
+			Badness:  p.probe.Badness,
+			Result:   r,
+			Start:    start,
+			Duration: time.Since(start),
+			Trace:    tc,
+		})
+	}
+	if located {
+		p.d.checkQuorum(p.probe)
+	}
+	return r
+}
+
+// checkRecovered clears any alert of the probe once it's no longer
+// alerting. Probes run in several locations recover by quorum instead.
+func (p trackedProber) checkRecovered(info probeInfo) {
+	if !p.probe.IsAlerting() && p.d.clearAlert(p.probe.Name, info) {
+		log.Printf("Probe %s has recovered\n", p.probe.Name)
+		p.d.incidents.probeRecovered(p.probe.Name)
+		p.d.publishAlert(p.probe.Name)
+	}
+}
+
+// probeRun is a run of a probe by the dashboard.
+type probeRun struct {
+	Name     string
+	Info     probeInfo
+	Badness  int
+	Result   prober.Result
+	Start    time.Time
+	Duration time.Duration
+	Trace    traceContext // zero unless runs are exported over OTLP
+}
+
+// resultSink exports the runs of the dashboard's probes elsewhere.
+type resultSink interface {
+	// record adds the run to the next export.
+	record(r probeRun)
+	// flush exports the runs recorded since the last export.
+	flush()
+	// flushLoop exports recorded runs, blocking until the context is
+	// done, when it exports them one last time.
+	flushLoop(ctx context.Context)
+}
+
+// recordRun records the result of the run in the history, publishes
+// it and passes it on to the sinks.
+func (d *Dashboard) recordRun(r probeRun) {
+	end := r.Start.Add(r.Duration)
+	d.history.record(r.Name, r.Result, end, r.Duration)
+	d.publishResult(r.Name, r.Result, end)
+	for _, s := range d.sinks {
+		s.record(r)
+	}
+}
+
+// Alert sends the alert through the dashboard's notifier, unless the
+// dashboard is stopping or the probe has been removed. Probes running
+// in several locations alert on a quorum of them instead.
+func (p trackedProber) Alert(name, desc string, badness int, records prober.Records) error {
+	if p.d.lifetime.Err() != nil || !p.d.registered(p.probe) {
+		return nil
+	}
+	if len(p.d.getProbeInfo(name).Locations) > 0 {
+		return nil
+	}
+	return p.d.sendAlert(name, desc, badness, records)
+}
+
+// getDnsExpectations describes the records a dns probe expects.
+func getDnsExpectations(cname string, a []string, mx []*net.MX, ns []*net.NS, txt []string) []string {
+	expect := []string{}
+	if cname != "" {
+		expect = append(expect, "CNAME "+cname)
+	}
+	for _, r := range a {
+		expect = append(expect, "A "+r)
+	}
+	for _, r := range mx {
+		expect = append(expect, fmt.Sprintf("MX %s (pref %d)", r.Host, r.Pref))
+	}
+	for _, r := range ns {
+		expect = append(expect, "NS "+r.Host)
+	}
+	for _, r := range txt {
+		expect = append(expect, fmt.Sprintf("TXT %q", r))
+	}
+	return expect
+}
+
+// loadProbes returns the probes in the config, sorted.
+func (l *probeLoader) loadProbes() prober.Probes {
+	probes := append(l.getDnsProbes(), l.getWebProbes()...)
+	probes = append(probes, l.getVarsProbes()...)
+	sort.Sort(probes)
+	return probes
+}
+
+// track routes the results and alerts of the probe through the
+// dashboard.
+func (d *Dashboard) track(p *prober.Probe) {
+	p.Prober = trackedProber{p.Prober, p, d}
 }
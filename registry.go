@@ -0,0 +1,164 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"hkjn.me/prober"
+)
+
+// ProbeOption configures a probe added with AddProbe.
+type ProbeOption func(*probeRegistration)
+
+// probeRegistration is how a probe added with AddProbe is configured.
+type probeRegistration struct {
+	info   probeInfo
+	policy string
+}
+
+// Labels attaches the key-value pairs to the probe, shown along with
+// it on the dashboard.
+func Labels(labels map[string]string) ProbeOption {
+	return func(r *probeRegistration) {
+		if r.info.Labels == nil {
+			r.info.Labels = map[string]string{}
+		}
+		for k, v := range labels {
+			r.info.Labels[k] = v
+		}
+	}
+}
+
+// EscalationPolicy routes alerts for the probe through the named
+// escalation policy in probes.yaml, instead of the default one.
+func EscalationPolicy(name string) ProbeOption {
+	return func(r *probeRegistration) {
+		r.policy = name
+	}
+}
+
+// Runbook adds a link to a runbook or docs for the probe, shown on the
+// dashboard and in its alerts.
+func Runbook(name, url string) ProbeOption {
+	return func(r *probeRegistration) {
+		r.info.Links = append(r.info.Links, link{Name: name, URL: url})
+	}
+}
+
+// Target describes what the probe checks, and what it expects, as shown
+// on its page.
+func Target(target string, expect ...string) ProbeOption {
+	return func(r *probeRegistration) {
+		r.info.Target = target
+		r.info.Expect = expect
+	}
+}
+
+// AddProbe adds the probe to the dashboard, where it's shown and
+// alerts the same way as probes in probes.yaml. If the dashboard has
+// been started, the probe starts running right away.
+//
+// The probe's name must be unique within the dashboard.
+func (d *Dashboard) AddProbe(p *prober.Probe, options ...ProbeOption) error {
+	if p == nil || p.Prober == nil {
+		return errors.New("no probe given")
+	}
+	if p.Name == "" {
+		return errors.New("probe has no name")
+	}
+	r := probeRegistration{info: probeInfo{Kind: "custom"}}
+	for _, o := range options {
+		o(&r)
+	}
+
+	d.probeLock.Lock()
+	if _, ok := d.policies[r.policy]; r.policy != "" && !ok {
+		d.probeLock.Unlock()
+		return fmt.Errorf("probe %s refers to unknown escalation policy %q", p.Name, r.policy)
+	}
+	for _, q := range d.probes {
+		if q.Name == p.Name {
+			d.probeLock.Unlock()
+			return fmt.Errorf("there's already a probe named %s", p.Name)
+		}
+	}
+	d.track(p)
+	probes := append(prober.Probes{p}, d.probes...)
+	sort.Sort(probes)
+	d.probes = probes
+	d.probeInfos[p.Name] = r.info
+	if r.policy != "" {
+		d.probePolicies[p.Name] = r.policy
+	}
+	run := d.started && !d.conf.ProberDisabled && r.info.runsHere()
+	d.probeLock.Unlock()
+
+	log.Printf("Added probe %s\n", p.Name)
+	if run {
+		go p.Run()
+	}
+	return nil
+}
+
+// RemoveProbe removes the probe with the name from the dashboard,
+// clearing any alert for it.
+func (d *Dashboard) RemoveProbe(name string) error {
+	d.probeLock.Lock()
+	probes := prober.Probes{}
+	for _, p := range d.probes {
+		if p.Name != name {
+			probes = append(probes, p)
+		}
+	}
+	if len(probes) == len(d.probes) {
+		d.probeLock.Unlock()
+		return fmt.Errorf("no probe named %s", name)
+	}
+	d.probes = probes
+	info := d.probeInfos[name]
+	delete(d.probeInfos, name)
+	delete(d.probePolicies, name)
+	d.probeLock.Unlock()
+
+	log.Printf("Removed probe %s\n", name)
+	if d.clearAlert(name, info) {
+		d.incidents.probeRemoved(name)
+		d.publishAlert(name)
+	}
+	return nil
+}
+
+// listProbes returns the probes in the dashboard, sorted.
+func (d *Dashboard) listProbes() prober.Probes {
+	d.probeLock.RLock()
+	defer d.probeLock.RUnlock()
+	return append(prober.Probes{}, d.probes...)
+}
+
+// registered returns true if the probe is in the dashboard.
+func (d *Dashboard) registered(p *prober.Probe) bool {
+	d.probeLock.RLock()
+	defer d.probeLock.RUnlock()
+	for _, q := range d.probes {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// getProbeConfig returns the probes config.
+func (d *Dashboard) getProbeConfig() probeConfig {
+	d.probeLock.RLock()
+	defer d.probeLock.RUnlock()
+	return d.probecfg
+}
+
+// getProbeInfo returns how the probe was configured.
+func (d *Dashboard) getProbeInfo(name string) probeInfo {
+	d.probeLock.RLock()
+	defer d.probeLock.RUnlock()
+	return d.probeInfos[name]
+}
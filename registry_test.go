@@ -0,0 +1,55 @@
+package dashboard
+
+import (
+	"testing"
+
+	"hkjn.me/prober"
+)
+
+// fakeProber always passes, and never alerts.
+type fakeProber struct{}
+
+func (fakeProber) Probe() prober.Result { return prober.Result{Passed: true} }
+
+func (fakeProber) Alert(name, desc string, badness int, records prober.Records) error { return nil }
+
+func TestAddRemoveProbe(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true})
+	p := prober.NewProbe(fakeProber{}, "Custom", "Custom check")
+	if err := d.AddProbe(p, Labels(map[string]string{"team": "ops"}), Runbook("Runbook", "https://example.com/runbook")); err != nil {
+		t.Fatalf("failed to add probe: %v\n", err)
+	}
+	if got := d.findProbe("Custom"); got != p {
+		t.Fatalf("want probe Custom in dashboard, got %v\n", got)
+	}
+	if got := d.getProbeInfo("Custom"); got.Labels["team"] != "ops" || len(got.Links) != 1 {
+		t.Fatalf("want labels and runbook for Custom, got %+v\n", got)
+	}
+	if err := d.AddProbe(prober.NewProbe(fakeProber{}, "Custom", "")); err == nil {
+		t.Fatalf("want error adding probe with duplicate name, got none\n")
+	}
+	if err := d.AddProbe(prober.NewProbe(fakeProber{}, "Other", ""), EscalationPolicy("missing")); err == nil {
+		t.Fatalf("want error adding probe with unknown escalation policy, got none\n")
+	}
+
+	d.alerts.raise("Custom")
+	d.incidents.probeAlerting("Custom")
+	if err := d.RemoveProbe("Custom"); err != nil {
+		t.Fatalf("failed to remove probe: %v\n", err)
+	}
+	if got := d.findProbe("Custom"); got != nil {
+		t.Fatalf("want probe Custom removed, got %v\n", got)
+	}
+	if got := d.alerts.get("Custom"); got.Alerting {
+		t.Fatalf("want alert for removed probe cleared, got %+v\n", got)
+	}
+	if i, _ := d.incidents.get(1); i.Open() {
+		t.Fatalf("want incident closed after probe removed, got %+v\n", i)
+	}
+	if got := p.Prober.Probe(); got != removedResult {
+		t.Fatalf("want %v from removed probe, got %v\n", removedResult, got)
+	}
+	if err := d.RemoveProbe("Custom"); err == nil {
+		t.Fatalf("want error removing missing probe, got none\n")
+	}
+}
@@ -0,0 +1,102 @@
+package dashboard
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+
+	"hkjn.me/prober"
+)
+
+// Reload reads the probes config again, replacing the probes, links,
+// escalation policies and status page it defines. Probes whose config
+// didn't change keep running undisturbed, and probes added with
+// AddProbe or for external checks are kept.
+//
+// If the config can't be loaded, the dashboard keeps running with the
+// old one.
+func (d *Dashboard) Reload() error {
+	var probecfg probeConfig
+	hash, err := loadProbesConfig(d.probeSource, d.probeFile, &probecfg)
+	if err != nil {
+		return fmt.Errorf("couldn't load probes config: %v", err)
+	}
+	l := newProbeLoader(probecfg)
+	l.webClient = d.webClient
+	loaded := l.loadProbes()
+	policies, err := loadEscalationPolicies(probecfg, l.policies, d.conf.EmailRecipient)
+	if err != nil {
+		return fmt.Errorf("couldn't load escalation policies: %v", err)
+	}
+
+	d.probeLock.Lock()
+	old := map[string]*prober.Probe{}
+	for _, p := range d.probes {
+		old[p.Name] = p
+	}
+	probes := prober.Probes{}
+	infos := map[string]probeInfo{}
+	probePolicies := map[string]string{}
+	for _, p := range d.probes {
+		kind := d.probeInfos[p.Name].Kind
+		if kind != "custom" && kind != "external" {
+			continue
+		}
+		if _, ok := l.infos[p.Name]; ok {
+			d.probeLock.Unlock()
+			return fmt.Errorf("probe %s in config is already added as a %s probe", p.Name, kind)
+		}
+		policy, ok := d.probePolicies[p.Name]
+		if _, exists := policies[policy]; ok && !exists {
+			d.probeLock.Unlock()
+			return fmt.Errorf("probe %s refers to escalation policy %q, which is no longer configured", p.Name, policy)
+		}
+		probes = append(probes, p)
+		infos[p.Name] = d.probeInfos[p.Name]
+		if ok {
+			probePolicies[p.Name] = policy
+		}
+	}
+	started := prober.Probes{}
+	for _, p := range loaded {
+		if q, ok := old[p.Name]; ok && reflect.DeepEqual(d.probeInfos[p.Name], l.infos[p.Name]) && d.probePolicies[p.Name] == l.policies[p.Name] {
+			p = q
+		} else {
+			d.track(p)
+			started = append(started, p)
+		}
+		probes = append(probes, p)
+		infos[p.Name] = l.infos[p.Name]
+		if policy, ok := l.policies[p.Name]; ok {
+			probePolicies[p.Name] = policy
+		}
+	}
+	sort.Sort(probes)
+	removed := map[string]probeInfo{}
+	for name := range old {
+		if _, ok := infos[name]; !ok {
+			removed[name] = d.probeInfos[name]
+		}
+	}
+	d.probes, d.probeInfos, d.probePolicies = probes, infos, probePolicies
+	d.probecfg, d.probeHash, d.policies = probecfg, hash, policies
+	run := d.started && !d.conf.ProberDisabled
+	d.probeLock.Unlock()
+
+	log.Printf("Reloaded probes config, %d probes new or changed and %d removed\n", len(started), len(removed))
+	for name, info := range removed {
+		if d.clearAlert(name, info) {
+			d.incidents.probeRemoved(name)
+			d.publishAlert(name)
+		}
+	}
+	if run {
+		for _, p := range started {
+			if infos[p.Name].runsHere() {
+				go p.Run()
+			}
+		}
+	}
+	return nil
+}
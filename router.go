@@ -1,69 +1,135 @@
 package dashboard
 
 import (
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
+	"path"
 
 	"github.com/gorilla/mux"
-
-	"hkjn.me/dashboard/gen"
 )
 
 var (
 	baseTmpls = []string{
 		"tmpl/base.tmpl",
 		"tmpl/scripts.tmpl",
-		"tmpl/style.tmpl",
 	}
 	indexTmpls = append(
 		baseTmpls,
 		"tmpl/index.tmpl",
 		"tmpl/links.tmpl",
 		"tmpl/prober.tmpl",
+		"tmpl/federation.tmpl",
+	)
+	incidentsTmpls = append(
+		baseTmpls,
+		"tmpl/incidents.tmpl",
+	)
+	incidentTmpls = append(
+		baseTmpls,
+		"tmpl/incident.tmpl",
+	)
+	ackTmpls = append(
+		baseTmpls,
+		"tmpl/ack.tmpl",
 	)
 	baseTemplate = "base"
+	// errNotFound is returned by getDataFn when there's nothing to
+	// render.
+	errNotFound = errors.New("not found")
 )
 
 // newRouter returns a new router for the endpoints of the dashboard.
 //
-// newRouter panics if the config wasn't loaded.
-func newRouter(debug bool) *mux.Router {
-	prefix := getHttpPrefix()
-	// xx: since we only have the index page, can parse template (
-	// via either bindata or .tmpl from disk) higher up in call chain than newPage().
-	r := newPage(prefix+"/", indexTmpls, getIndexData, debug)
-
-	router := mux.NewRouter().StrictSlash(true)
-	log.Printf("Registering route for %q on %q\n", r.Method(), r.Pattern())
-	router.
-		Methods(r.Method()).
-		Path(r.Pattern()).
-		HandlerFunc(r.HandlerFunc())
+// newRouter panics if the templates can't be parsed.
+func (d *Dashboard) newRouter() *mux.Router {
+	prefix := d.conf.HttpPrefix
+	index := d.newPage(prefix+"/", indexTmpls, d.getIndexData)
+
+	routes := []route{
+		index,
+		simpleRoute{prefix + "/ack", "GET", d.confirmAckLink},
+		simpleRoute{prefix + "/ack/link", "POST", d.ackFromLink},
+		simpleRoute{prefix + "/ack", "POST", d.withActionRoles(d.ackFromForm)},
+		simpleRoute{prefix + "/events", "GET", d.serveEvents},
+		simpleRoute{prefix + "/static/{name}", "GET", d.serveStatic},
+		d.newPage(prefix+"/probes/{name}", probeTmpls, d.getProbeData),
+		simpleRoute{prefix + "/probes/{name}/run", "POST", d.withActionRoles(d.runFromForm)},
+		simpleRoute{prefix + "/probes/{name}/pause", "POST", d.withActionRoles(d.pauseFromForm)},
+		simpleRoute{prefix + "/probes/{name}/resume", "POST", d.withActionRoles(d.resumeFromForm)},
+		d.newPage(prefix+"/incidents", incidentsTmpls, d.getIncidentsData),
+		d.newPage(prefix+"/incidents/{id:[0-9]+}", incidentTmpls, d.getIncidentData),
+		simpleRoute{prefix + "/incidents/{id:[0-9]+}/notes", "POST", d.withActionRoles(d.addIncidentNote)},
+		// Health checks are for orchestrators, so they're never behind
+		// auth.
+		simpleRoute{prefix + "/healthz", "GET", d.serveHealthz},
+		simpleRoute{prefix + "/readyz", "GET", d.serveReadyz},
+		simpleRoute{prefix + "/version", "GET", d.serveVersion},
+		simpleRoute{prefix + "/api/status", "GET", d.withToken(d.apiStatus)},
+		simpleRoute{prefix + "/api/probes", "GET", d.withToken(d.apiProbes)},
+		simpleRoute{prefix + "/api/probes/{name}", "GET", d.withToken(d.apiProbe)},
+		simpleRoute{prefix + "/api/probes/{name}/results", "GET", d.withToken(d.apiResults)},
+		simpleRoute{prefix + "/api/probes/{name}/ack", "POST", d.withToken(d.apiAck)},
+		simpleRoute{prefix + "/api/probes/{name}/run", "POST", d.withToken(d.apiRun)},
+		simpleRoute{prefix + "/api/probes/{name}/pause", "POST", d.withToken(d.apiPause)},
+		simpleRoute{prefix + "/api/probes/{name}/resume", "POST", d.withToken(d.apiResume)},
+		simpleRoute{prefix + "/api/results", "POST", d.withToken(d.apiPushResults)},
+		simpleRoute{prefix + "/api/checks", "POST", d.withToken(d.apiChecks)},
+		simpleRoute{prefix + "/api/agent/probes", "GET", d.withAgentToken(d.apiAgentProbes)},
+		simpleRoute{prefix + "/api/agent/results", "POST", d.withAgentToken(d.apiAgentResults)},
+		simpleRoute{prefix + "/api/silences", "GET", d.withToken(d.apiSilences)},
+		simpleRoute{prefix + "/api/silences", "POST", d.withToken(d.apiAddSilence)},
+		simpleRoute{prefix + "/api/silences/{id:[0-9]+}", "DELETE", d.withToken(d.apiExpireSilence)},
+		simpleRoute{prefix + "/api/reload", "POST", d.withToken(d.apiReload)},
+	}
+	if d.conf.StatusAddr == "" && len(d.probecfg.StatusPage.Components) > 0 {
+		routes = append(routes, d.newPage(prefix+d.conf.StatusPath, statusTmpls, d.getStatusData))
+	}
+
+	return registerRoutes(routes)
+}
+
+// newStatusRouter returns a new router serving only the public status
+// page on the pattern.
+func (d *Dashboard) newStatusRouter(pattern string) *mux.Router {
+	return registerRoutes([]route{
+		d.newPage(pattern, statusTmpls, d.getStatusData),
+		simpleRoute{d.conf.HttpPrefix + "/static/{name}", "GET", d.serveStatic},
+	})
+}
+
+// registerRoutes returns a new router for the routes, setting
+// security headers on all responses.
+func registerRoutes(routes []route) *mux.Router {
+	// Paths are matched encoded, so probe names may contain slashes.
+	router := mux.NewRouter().StrictSlash(true).UseEncodedPath()
+	for _, r := range routes {
+		log.Printf("Registering route for %q on %q\n", r.Method(), r.Pattern())
+		router.
+			Methods(r.Method()).
+			Path(r.Pattern()).
+			Handler(withSecurityHeaders(r.HandlerFunc()))
+	}
 	return router
 }
 
-func getHttpPrefix() string {
-	return os.Getenv("DASHBOARD_HTTP_PREFIX")
+// getTemplate returns the template parsed from the paths in the assets.
+func (d *Dashboard) getTemplate(tmpls []string) (*template.Template, error) {
+	return template.New(path.Base(tmpls[0])).
+		Funcs(template.FuncMap{"static": d.getStaticURL, "pathEscape": url.PathEscape}).
+		ParseFS(d.assets, tmpls...)
 }
 
-// getTemplate returns the template loaded from the paths.
-//
-// getTemplate parses the .tmpl files from disk.
-func getTemplate(tmpls []string, debug bool) *template.Template {
-	if !debug {
-		s := ""
-		for _, filename := range tmpls {
-			b, err := gen.Asset(filename)
-			if err != nil {
-				log.Fatalf("failed to fetch .tmpl with Asset(): %v\n", err)
-			}
-			s += string(b)
-		}
-		return template.Must(template.New("main").Parse(s))
+// getProbeName returns the name of the probe in the request's path,
+// which is matched encoded.
+func getProbeName(r *http.Request) string {
+	name := mux.Vars(r)["name"]
+	if n, err := url.PathUnescape(name); err == nil {
+		return n
 	}
-	return template.Must(template.ParseFiles(tmpls...))
+	return name
 }
 
 // serveISE serves an internal server error to the user.
@@ -95,16 +161,22 @@ type getDataFn func(http.ResponseWriter, *http.Request) (interface{}, error)
 
 // page implements the route interface for endpoints that render HTML.
 type page struct {
+	d               *Dashboard
 	pattern         string
+	tmpls           []string           // paths of the template files
 	tmpl            *template.Template // backing template
 	getTemplateData getDataFn
 }
 
 // newPage returns a new page.
-func newPage(pattern string, tmpls []string, getData getDataFn, debug bool) *page {
+//
+// newPage panics if the templates can't be parsed.
+func (d *Dashboard) newPage(pattern string, tmpls []string, getData getDataFn) *page {
 	return &page{
+		d,
 		pattern,
-		getTemplate(tmpls, debug),
+		tmpls,
+		template.Must(d.getTemplate(tmpls)),
 		getData,
 	}
 }
@@ -118,12 +190,24 @@ func (p page) Pattern() string { return p.pattern }
 func (p page) HandlerFunc() http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		data, err := p.getTemplateData(w, r)
-		if err != nil {
+		if err == errNotFound {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
 			log.Printf("error getting template data: %v\n", err)
 			serveISE(w)
 			return
 		}
-		err = p.tmpl.ExecuteTemplate(w, baseTemplate, data)
+		tmpl := p.tmpl
+		if p.d.reloadTemplates {
+			tmpl, err = p.d.getTemplate(p.tmpls)
+			if err != nil {
+				log.Printf("error parsing templates: %v\n", err)
+				serveISE(w)
+				return
+			}
+		}
+		err = tmpl.ExecuteTemplate(w, baseTemplate, data)
 		if err != nil {
 			log.Printf("error rendering template: %v\n", err)
 			serveISE(w)
@@ -0,0 +1,108 @@
+package dashboard
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+// ProbeResult is the result of a probe run.
+type ProbeResult struct {
+	Name     string        `json:"name"`
+	Time     time.Time     `json:"time"`
+	Passed   bool          `json:"passed"`
+	Info     string        `json:"info"`
+	Duration time.Duration `json:"duration"`
+}
+
+// RunOnce runs each probe matching any of the patterns once,
+// concurrently, and returns their results in name order. All probes
+// run if no patterns are given. Patterns are as for path.Match.
+//
+// Probes still running once the context is done fail. Runs are
+// recorded, but neither alert nor clear alerts, and the dashboard
+// doesn't need to be started. The runs are
+// exported to any sinks, like OTLP, before RunOnce returns.
+func (d *Dashboard) RunOnce(ctx context.Context, patterns ...string) []ProbeResult {
+	probes := prober.Probes{}
+	for _, p := range d.listProbes() {
+		if matchesAny(p.Name, patterns) {
+			probes = append(probes, p)
+		}
+	}
+	results := runProbes(ctx, probes, d.runOnce)
+	for _, s := range d.sinks {
+		s.flush()
+	}
+	return results
+}
+
+// runOnce runs the underlying prober of the probe once and records
+// the run, without raising or clearing alerts or checking quorums.
+func (d *Dashboard) runOnce(p *prober.Probe) prober.Result {
+	tp, ok := p.Prober.(trackedProber)
+	if !ok {
+		return p.Prober.Probe()
+	}
+	tc := d.startTrace()
+	start := time.Now()
+	r := runTraced(tp.Prober, tc)
+	d.recordRun(probeRun{
+		Name:     p.Name,
+		Info:     d.getProbeInfo(p.Name),
+		Badness:  p.Badness,
+		Result:   r,
+		Start:    start,
+		Duration: time.Since(start),
+		Trace:    tc,
+	})
+	return r
+}
+
+// probeOnce runs the probe once.
+func probeOnce(p *prober.Probe) prober.Result {
+	return p.Prober.Probe()
+}
+
+// runProbes runs each of the probes once with run, concurrently, and
+// returns their results in the same order. Probes still running once
+// the context is done fail.
+func runProbes(ctx context.Context, probes prober.Probes, run func(p *prober.Probe) prober.Result) []ProbeResult {
+	results := make([]ProbeResult, len(probes))
+	wg := sync.WaitGroup{}
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p *prober.Probe) {
+			defer wg.Done()
+			start := time.Now()
+			c := make(chan prober.Result, 1)
+			go func() { c <- run(p) }()
+			var r prober.Result
+			select {
+			case r = <-c:
+			case <-ctx.Done():
+				r = prober.Result{Info: "timed out: " + ctx.Err().Error()}
+			}
+			results[i] = ProbeResult{p.Name, start, r.Passed, r.Info, time.Since(start)}
+		}(i, p)
+	}
+	wg.Wait()
+	return results
+}
+
+// matchesAny returns true if the name matches any of the patterns, or
+// there are none.
+func matchesAny(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
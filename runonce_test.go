@@ -0,0 +1,104 @@
+package dashboard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+// resultProber always returns the result, and never alerts.
+type resultProber prober.Result
+
+func (r resultProber) Probe() prober.Result { return prober.Result(r) }
+
+func (resultProber) Alert(name, desc string, badness int, records prober.Records) error { return nil }
+
+// countingSink counts the runs recorded and exported.
+type countingSink struct {
+	sync.Mutex
+	recorded, flushed int
+}
+
+func (s *countingSink) record(probeRun) {
+	s.Lock()
+	defer s.Unlock()
+	s.recorded++
+}
+
+func (s *countingSink) flush() {
+	s.Lock()
+	defer s.Unlock()
+	s.flushed = s.recorded
+}
+
+func (*countingSink) flushLoop(ctx context.Context) {}
+
+func TestRunOnce(t *testing.T) {
+	d := newTestDashboard(t, Config{Debug: true})
+	sink := &countingSink{}
+	d.sinks = append(d.sinks, sink)
+	d.AddProbe(prober.NewProbe(resultProber{Passed: true, Info: "fine"}, "WebIndex", ""))
+	d.AddProbe(prober.NewProbe(resultProber{Passed: false, Info: "down"}, "WebStatus", ""))
+	d.AddProbe(prober.NewProbe(resultProber{Passed: true}, "DnsRoot", ""))
+	cases := []struct {
+		patterns []string
+		want     []ProbeResult
+	}{
+		{nil, []ProbeResult{{Name: "DnsRoot", Passed: true}, {Name: "WebIndex", Passed: true, Info: "fine"}, {Name: "WebStatus", Info: "down"}}},
+		{[]string{"Web*"}, []ProbeResult{{Name: "WebIndex", Passed: true, Info: "fine"}, {Name: "WebStatus", Info: "down"}}},
+		{[]string{"DnsRoot", "Missing"}, []ProbeResult{{Name: "DnsRoot", Passed: true}}},
+	}
+	for i, tt := range cases {
+		got := d.RunOnce(context.Background(), tt.patterns...)
+		if len(got) != len(tt.want) {
+			t.Fatalf("[%d] want %d results, got %+v\n", i, len(tt.want), got)
+		}
+		for j, w := range tt.want {
+			got[j].Time, got[j].Duration = time.Time{}, 0
+			if got[j] != w {
+				t.Fatalf("[%d] want result %+v, got %+v\n", i, w, got[j])
+			}
+		}
+		if sink.flushed != sink.recorded {
+			t.Fatalf("[%d] want %d runs exported to sinks, got %d\n", i, sink.recorded, sink.flushed)
+		}
+	}
+	if sink.recorded != 6 {
+		t.Fatalf("want 6 runs recorded by sinks, got %d\n", sink.recorded)
+	}
+}
+
+func TestRunOnceNoAlerts(t *testing.T) {
+	lock := sync.Mutex{}
+	posts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		posts++
+	}))
+	defer srv.Close()
+
+	d := newTestDashboard(t, Config{Debug: true, AlertmanagerURL: srv.URL})
+	d.AddProbe(prober.NewProbe(resultProber{Info: "down"}, "WebIndex", ""), Locations(1, "central"))
+	d.AddProbe(prober.NewProbe(resultProber{Passed: true}, "WebStatus", ""))
+	d.alerts.raise("WebStatus")
+	for i := 0; i < 2; i++ {
+		d.RunOnce(context.Background())
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if posts != 0 {
+		t.Fatalf("want no requests to Alertmanager from one-shot runs, got %d\n", posts)
+	}
+	if got := d.alerts.get("WebIndex"); got.Alerting {
+		t.Fatalf("want no alert raised by one-shot runs, got %+v\n", got)
+	}
+	if got := d.alerts.get("WebStatus"); !got.Alerting {
+		t.Fatalf("want no alert cleared by one-shot runs, got %+v\n", got)
+	}
+}
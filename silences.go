@@ -0,0 +1,191 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Silence stops notifications for alerts of the probes matching a
+// pattern, for a while.
+type Silence struct {
+	ID      int       `json:"id"`
+	Pattern string    `json:"pattern"` // probe names silenced, as for path.Match
+	By      string    `json:"by"`
+	Comment string    `json:"comment"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// Active returns true if the silence is in effect at the time.
+func (s Silence) Active(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// Matches returns true if the silence covers the probe.
+func (s Silence) Matches(probe string) bool {
+	ok, _ := path.Match(s.Pattern, probe)
+	return ok
+}
+
+// silenceBook holds all silences, persisting them to a file if set.
+type silenceBook struct {
+	sync.Mutex
+	all  []*Silence
+	file string
+}
+
+// load reads the silences from the file, if it exists.
+func (b *silenceBook) load(file string) error {
+	b.Lock()
+	defer b.Unlock()
+	b.file = file
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &b.all)
+}
+
+// save writes the silences to the file, if set. The lock must be held.
+func (b *silenceBook) save() {
+	if b.file == "" {
+		return
+	}
+	data, err := json.Marshal(b.all)
+	if err == nil {
+		err = writeFileAtomic(b.file, data)
+	}
+	if err != nil {
+		log.Printf("Failed to save silences to %s: %v\n", b.file, err)
+	}
+}
+
+// replace replaces all silences by copies of the ones given, as shared
+// by other dashboards.
+func (b *silenceBook) replace(all []*Silence) {
+	b.Lock()
+	defer b.Unlock()
+	b.all = []*Silence{}
+	for _, s := range all {
+		c := *s
+		b.all = append(b.all, &c)
+	}
+	b.save()
+}
+
+// copies returns copies of all silences, in the order they were added.
+func (b *silenceBook) copies() []*Silence {
+	b.Lock()
+	defer b.Unlock()
+	all := []*Silence{}
+	for _, s := range b.all {
+		c := *s
+		all = append(all, &c)
+	}
+	return all
+}
+
+// add adds a silence for the probes matching the pattern, lasting for
+// the duration from now.
+func (b *silenceBook) add(pattern, by, comment string, d time.Duration) (Silence, error) {
+	if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+		return Silence{}, errors.New("bad probe pattern")
+	}
+	if by == "" {
+		return Silence{}, errors.New("no name given for silence")
+	}
+	if d <= 0 {
+		return Silence{}, errors.New("silence must last a while")
+	}
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	s := &Silence{
+		ID:      len(b.all) + 1,
+		Pattern: pattern,
+		By:      by,
+		Comment: comment,
+		Start:   now,
+		End:     now.Add(d),
+	}
+	b.all = append(b.all, s)
+	log.Printf("Silenced %s until %v for %s: %s\n", pattern, s.End, by, comment)
+	b.save()
+	return *s, nil
+}
+
+// expire ends the silence with the ID now.
+func (b *silenceBook) expire(id int) error {
+	b.Lock()
+	defer b.Unlock()
+	if id < 1 || id > len(b.all) {
+		return errNotFound
+	}
+	s := b.all[id-1]
+	now := time.Now()
+	if !s.Active(now) {
+		return errors.New("silence is not active")
+	}
+	s.End = now
+	log.Printf("Expired silence %d of %s\n", id, s.Pattern)
+	b.save()
+	return nil
+}
+
+// list returns copies of all silences, most recent first, optionally
+// only the active ones.
+func (b *silenceBook) list(activeOnly bool) []Silence {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	ss := []Silence{}
+	for _, s := range b.all {
+		if !activeOnly || s.Active(now) {
+			ss = append(ss, *s)
+		}
+	}
+	sort.Slice(ss, func(i, j int) bool { return ss[i].ID > ss[j].ID })
+	return ss
+}
+
+// silenced returns the active silence covering the probe, if any.
+func (b *silenceBook) silenced(probe string) (Silence, bool) {
+	for _, s := range b.list(true) {
+		if s.Matches(probe) {
+			return s, true
+		}
+	}
+	return Silence{}, false
+}
+
+// addSilence adds a silence, shared with other dashboards if the lease
+// keeps shared state. Alerts posted to Alertmanager must be silenced
+// there instead.
+func (d *Dashboard) addSilence(pattern, by, comment string, dur time.Duration) (Silence, error) {
+	if _, ok := d.notifier.(resolver); ok {
+		return Silence{}, errAlertmanager
+	}
+	s := Silence{}
+	err := d.shareState(func() (err error) {
+		s, err = d.silences.add(pattern, by, comment, dur)
+		return err
+	})
+	return s, err
+}
+
+// expireSilence ends the silence with the ID now, also for other
+// dashboards if the lease keeps shared state.
+func (d *Dashboard) expireSilence(id int) error {
+	return d.shareState(func() error {
+		return d.silences.expire(id)
+	})
+}
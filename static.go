@@ -0,0 +1,72 @@
+package dashboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// contentSecurityPolicy only allows the dashboard's own scripts, styles
+// and connections. Images are allowed from anywhere over https, for
+// icons of configured links.
+const contentSecurityPolicy = "default-src 'self'; img-src 'self' data: https:; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// getStaticHash returns a short hash of the contents of the static
+// file, or "" if it can't be read.
+func (d *Dashboard) getStaticHash(name string) string {
+	d.staticHashes.Lock()
+	defer d.staticHashes.Unlock()
+	if h, ok := d.staticHashes.m[name]; ok && !d.reloadTemplates {
+		return h
+	}
+	b, err := fs.ReadFile(d.assets, path.Join("static", name))
+	if err != nil {
+		log.Printf("Failed to read static file %q: %v\n", name, err)
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	h := hex.EncodeToString(sum[:])[:12]
+	d.staticHashes.m[name] = h
+	return h
+}
+
+// getStaticURL returns the URL of the static file, which changes
+// whenever the file does so that it can be cached indefinitely.
+func (d *Dashboard) getStaticURL(name string) string {
+	return d.conf.HttpPrefix + "/static/" + name + "?v=" + d.getStaticHash(name)
+}
+
+// serveStatic serves a static file, allowing clients to cache it for
+// as long as they like if the request is for the current version.
+func (d *Dashboard) serveStatic(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	b, err := fs.ReadFile(d.assets, path.Join("static", name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if v := r.FormValue("v"); v != "" && v == d.getStaticHash(name) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(b))
+}
+
+// withSecurityHeaders sets the Content-Security-Policy and related
+// headers on all responses of the handler.
+func withSecurityHeaders(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		h.ServeHTTP(w, r)
+	})
+}
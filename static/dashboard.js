@@ -0,0 +1,112 @@
+// dashboard.js: client-side behaviour of the dashboard.
+
+// initToggle lets the probe results be shown and hidden.
+function initToggle() {
+  var show = document.querySelector(".show");
+  var hide = document.querySelector(".hide");
+  var info = document.getElementById("probe_info");
+  if (!show || !hide || !info) {
+    return;
+  }
+  show.hidden = true;
+  show.addEventListener("click", function(e) {
+    e.preventDefault();
+    show.hidden = true;
+    hide.hidden = false;
+    info.hidden = false;
+  });
+  hide.addEventListener("click", function(e) {
+    e.preventDefault();
+    hide.hidden = true;
+    show.hidden = false;
+    info.hidden = true;
+  });
+}
+
+// findProbe returns the element for the probe on the page, if any.
+function findProbe(name) {
+  var probes = document.querySelectorAll(".probe");
+  for (var i = 0; i < probes.length; i++) {
+    if (probes[i].dataset.probe === name) {
+      return probes[i];
+    }
+  }
+  return null;
+}
+
+// addResult adds the result of a probe run, dropping the oldest ones
+// beyond the limit set by liveUpdate.
+function addResult(e) {
+  var probe = findProbe(e.probe);
+  if (!probe) {
+    return;
+  }
+  var results = probe.querySelector(".probe_results");
+  if (!results) {
+    return;
+  }
+  var div = document.createElement("div");
+  div.className = "probe_result " + (e.passed ? "good" : "bad");
+  var mark = document.createElement("strong");
+  mark.title = e.time + (e.info ? ": " + e.info : "");
+  mark.textContent = e.passed ? "\u2713" : "x";
+  div.appendChild(mark);
+  results.appendChild(div);
+  while (results.children.length > results.dataset.max) {
+    results.removeChild(results.firstElementChild);
+  }
+}
+
+// setAlert updates the alert state of a probe.
+function setAlert(e) {
+  var probe = findProbe(e.probe);
+  if (!probe) {
+    return;
+  }
+  // Disabled and paused probes show no badness, and may not be acked.
+  var badness = probe.querySelector(".badness");
+  if (badness) {
+    badness.classList.toggle("bad", !!e.alerting);
+  }
+  var acked = probe.querySelector(".acked");
+  if (acked) {
+    acked.hidden = !e.ackedBy;
+    acked.querySelector(".acked_by").textContent = e.ackedBy || "";
+    acked.querySelector(".acked_at").textContent = e.ackedAt || "";
+  }
+  var ack = probe.querySelector(".ack");
+  if (ack) {
+    ack.hidden = !e.alerting || !!e.ackedBy;
+  }
+}
+
+// liveUpdate updates probe results and alert state as events arrive
+// from the server.
+function liveUpdate() {
+  var live = document.getElementById("live");
+  if (!live || !window.EventSource) {
+    return;
+  }
+  var results = document.querySelectorAll(".probe_results");
+  for (var i = 0; i < results.length; i++) {
+    results[i].dataset.max = Math.max(results[i].children.length, 100);
+  }
+  var source = new EventSource("events");
+  source.onopen = function() {
+    live.hidden = false;
+  };
+  source.onerror = function() {
+    live.hidden = true;
+  };
+  source.addEventListener("result", function(m) {
+    addResult(JSON.parse(m.data));
+  });
+  source.addEventListener("alert", function(m) {
+    setAlert(JSON.parse(m.data));
+  });
+}
+
+document.addEventListener("DOMContentLoaded", function() {
+  initToggle();
+  liveUpdate();
+});
@@ -0,0 +1,115 @@
+package dashboard
+
+import (
+	"net/http"
+	"time"
+)
+
+var statusTmpls = []string{
+	"tmpl/status/base.tmpl",
+	"tmpl/status/status.tmpl",
+}
+
+// statusComponent is a part of the service shown on the public status
+// page, backed by one or more probes.
+type statusComponent struct {
+	Name   string
+	State  string // "operational", "degraded" or "outage"
+	Uptime float64
+	Days   []dayStats
+}
+
+// statusNotice is a notice about an ongoing incident on the public
+// status page.
+type statusNotice struct {
+	Since      time.Time
+	Components []string
+}
+
+// Class returns the CSS class for the day on the status page.
+func (d dayStats) Class() string {
+	switch u := d.Uptime(); {
+	case d.Total() == 0:
+		return "nodata"
+	case u >= 99.5:
+		return "up"
+	case u >= 90:
+		return "partial"
+	default:
+		return "down"
+	}
+}
+
+// getComponentState returns the state of a component backed by the
+// probes.
+func (d *Dashboard) getComponentState(probes []string) string {
+	alerting := 0
+	for _, p := range probes {
+		if d.alerts.get(p).Alerting {
+			alerting++
+		}
+	}
+	switch {
+	case alerting == 0:
+		return "operational"
+	case alerting < len(probes):
+		return "degraded"
+	default:
+		return "outage"
+	}
+}
+
+// getStatusData returns the data for the public status page.
+//
+// Only component names, states and uptime are shown, as the page is
+// meant for people outside of ops.
+func (d *Dashboard) getStatusData(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	probecfg := d.getProbeConfig()
+	data := struct {
+		Title      string
+		Components []statusComponent
+		Notices    []statusNotice
+	}{
+		Title: probecfg.StatusPage.Title,
+	}
+	if data.Title == "" {
+		data.Title = "Status"
+	}
+	affected := map[string][]string{} // components by probe
+	for _, c := range probecfg.StatusPage.Components {
+		sc := statusComponent{
+			Name:  c.Name,
+			State: d.getComponentState(c.Probes),
+			Days:  d.history.uptime(c.Probes, uptimeDays),
+		}
+		total := dayStats{}
+		for _, day := range sc.Days {
+			total.Passed += day.Passed
+			total.Failed += day.Failed
+		}
+		sc.Uptime = total.Uptime()
+		data.Components = append(data.Components, sc)
+		for _, p := range c.Probes {
+			affected[p] = append(affected[p], c.Name)
+		}
+	}
+	for _, i := range d.incidents.list() {
+		if !i.Open() {
+			continue
+		}
+		n := statusNotice{Since: i.Start}
+		seen := map[string]bool{}
+		for _, p := range i.Probes {
+			for _, c := range affected[p] {
+				if !seen[c] {
+					seen[c] = true
+					n.Components = append(n.Components, c)
+				}
+			}
+		}
+		if len(n.Components) > 0 {
+			data.Notices = append(data.Notices, n)
+		}
+	}
+	return data, nil
+}